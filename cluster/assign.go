@@ -0,0 +1,169 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/25 19:40
+ */
+
+package cluster
+
+import (
+	"math"
+
+	"github.com/yinyajun/golearn/matrix"
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+// 谱嵌入（n×k，每列一个特征向量）到类别的分配方法
+const (
+	KMeansAssign     = "kmeans"
+	DiscretizeAssign = "discretize"
+	ClusterQRAssign  = "cluster_qr"
+)
+
+const (
+	discretizeMaxIter = 30
+	discretizeTol     = 1e-5
+)
+
+// Discretize 多类谱聚类的离散化（Yu & Shi, 2003）
+// 寻找正交旋转R，使得行归一化的嵌入V·R最接近一个指示矩阵X：交替地取X为V·R每行的argmax，
+// 再由X^T·V的SVD（U S W^T）更新R = W·U^T，直到目标2(n - tr(S))不再变化。
+// 初始的R由彼此最不相似的k行构成（以第0行为起点），结果是确定的
+func Discretize(vectors mat.Matrix) ([]int, error) {
+	n, k := vectors.Dims()
+	if n == 0 || k == 0 {
+		return nil, ErrEmptyInput
+	}
+	v := mat.DenseCopyOf(vectors)
+	for j := 0; j < k; j++ {
+		col := v.ColView(j).(*mat.VecDense)
+		norm := mat.Norm(col, 2)
+		if norm == 0 {
+			continue
+		}
+		// 特征向量的符号不确定，统一为第一个非零元素为正
+		s := math.Sqrt(float64(n)) / norm
+		for i := 0; i < n; i++ {
+			if x := col.AtVec(i); x != 0 {
+				s = math.Copysign(s, x)
+				break
+			}
+		}
+		col.ScaleVec(s, col)
+	}
+	matrix.DenseNormalizeRows(v)
+
+	var (
+		rot    = mat.NewDense(k, k, nil)
+		c      = make([]float64, n)
+		sim    mat.VecDense
+		proj   mat.Dense
+		svd    mat.SVD
+		u, w   mat.Dense
+		last   float64
+		labels = make([]int, n)
+	)
+	rot.SetCol(0, v.RawRowView(0))
+	for j := 1; j < k; j++ {
+		sim.MulVec(v, rot.ColView(j-1))
+		for i := range c {
+			c[i] += math.Abs(sim.AtVec(i))
+		}
+		rot.SetCol(j, v.RawRowView(floats.MinIdx(c)))
+	}
+	for iter := 0; iter < discretizeMaxIter; iter++ {
+		proj.Mul(v, rot)
+		t := mat.NewDense(k, k, nil) // X^T·V
+		for i := range labels {
+			labels[i] = floats.MaxIdx(proj.RawRowView(i))
+			floats.Add(t.RawRowView(labels[i]), v.RawRowView(i))
+		}
+		if !svd.Factorize(t, mat.SVDFull) {
+			return nil, ErrSVDFactorization
+		}
+		ncut := 2 * (float64(n) - floats.Sum(svd.Values(nil)))
+		if math.Abs(ncut-last) < discretizeTol {
+			break
+		}
+		last = ncut
+		svd.UTo(&u)
+		svd.VTo(&w)
+		rot.Mul(&w, u.T())
+	}
+	return labels, nil
+}
+
+// ClusterQR 基于列主元QR的类别分配（Damle, Minden & Ying, 2019）
+// 对V^T做列主元QR，主元为k个最“正交”的样本；由这k行V_p的SVD（U S W^T）得到旋转R = U·W^T，
+// 每个样本归入|V·R|最大的一列。不需要迭代与初始化，结果是确定的
+func ClusterQR(vectors mat.Matrix) ([]int, error) {
+	n, k := vectors.Dims()
+	if n == 0 || k == 0 {
+		return nil, ErrEmptyInput
+	}
+	if k > n {
+		return nil, &ParamError{Field: "vectors.Cols", Value: k}
+	}
+	var (
+		v     = mat.DenseCopyOf(vectors)
+		pivot = mat.NewDense(k, k, nil) // V_p^T
+		rot   mat.Dense
+		svd   mat.SVD
+		u, w  mat.Dense
+	)
+	for t, p := range qrPivots(v, k) {
+		pivot.SetCol(t, v.RawRowView(p))
+	}
+	if !svd.Factorize(pivot, mat.SVDFull) {
+		return nil, ErrSVDFactorization
+	}
+	svd.UTo(&u)
+	svd.VTo(&w)
+	rot.Mul(&u, w.T())
+
+	var (
+		proj   mat.Dense
+		labels = make([]int, n)
+	)
+	proj.Mul(v, &rot)
+	for i := range labels {
+		row := proj.RawRowView(i)
+		for j := range row {
+			row[j] = math.Abs(row[j])
+		}
+		labels[i] = floats.MaxIdx(row)
+	}
+	return labels, nil
+}
+
+// qrPivots V^T列主元QR的前k个主元（即V的行），用修正的Gram-Schmidt逐个选取残差范数最大的行
+func qrPivots(v *mat.Dense, k int) []int {
+	var (
+		n, _  = v.Dims()
+		res   = mat.DenseCopyOf(v)
+		norms = make([]float64, n)
+		pivot = make([]int, k)
+		used  = make([]bool, n)
+	)
+	for i := 0; i < n; i++ {
+		norms[i] = floats.Dot(res.RawRowView(i), res.RawRowView(i))
+	}
+	for t := 0; t < k; t++ {
+		p := floats.MaxIdx(norms)
+		pivot[t], used[p] = p, true
+		q := append([]float64(nil), res.RawRowView(p)...)
+		if norm := math.Sqrt(norms[p]); norm > 0 {
+			floats.Scale(1/norm, q)
+		}
+		for i := 0; i < n; i++ {
+			if used[i] { // 已选的主元不再被选
+				norms[i] = math.Inf(-1)
+				continue
+			}
+			row := res.RawRowView(i)
+			floats.AddScaled(row, -floats.Dot(row, q), q)
+			norms[i] = floats.Dot(row, row)
+		}
+	}
+	return pivot
+}
@@ -6,8 +6,11 @@
 package cluster
 
 import (
+	"context"
+	"log"
 	"math"
 
+	"github.com/yinyajun/golearn/matrix"
 	"github.com/yinyajun/golearn/utils"
 	"gonum.org/v1/gonum/mat"
 )
@@ -18,41 +21,105 @@ const (
 )
 
 type SpecBisection struct {
-	Similarities *mat.SymDense // 数据点之间的相似度矩阵
+	Similarities mat.Symmetric // 数据点之间的相似度矩阵（可以是稠密的*mat.SymDense或稀疏的*matrix.SymCSR）
 	MinCut       bool          // 是否是min-cut
 	CutType      string        // ratioCut or nCut
+	Solver       string        // 特征分解方法："dense"或"lanczos"（只求所需的2个特征向量）
 	Verbose      bool          // 冗余模式
-	Strict       bool          // 严格二分
+	Strict       bool          // 严格二分：balance使两侧的权重满足Imbalance
+	Refine       bool          // 二分（以及balance）之后是否用FM算法局部优化cut（移动过程中保持Imbalance）
+	Weights      []float64     // 节点权重（例如负载），nil时每个节点的权重为1
+	Imbalance    float64       // 允许的不平衡度：每一侧的权重不超过(1+Imbalance)*W/2（W为总权重），默认0即严格对半；为0时非Strict的Refine使用0.1
+	MaxPasses    int           // FM的最大轮数
 	labels       []bool        // 二分结果
-	major        bool          // 个数最多的是哪类
+	major        bool          // 权重较大的是哪一侧
+	initial      *CutReport    // balance之前的诊断信息
+	final        *CutReport    // 最终二分的诊断信息
 	done         bool
 }
 
-func NewSpecBisection(sim *mat.SymDense) *SpecBisection {
+// CutReport 二分结果的诊断信息，下标0为false一侧，下标1为true一侧
+type CutReport struct {
+	CutWeight float64    // 两侧之间边的权重之和 cut(A,B)
+	RatioCut  float64    // cut(A,B)/|A| + cut(A,B)/|B|
+	NCut      float64    // cut(A,B)/vol(A) + cut(A,B)/vol(B)
+	Sizes     [2]int     // 两侧的样本数
+	Weights   [2]float64 // 两侧的节点权重之和
+	Volumes   [2]float64 // 两侧的体积（类内所有点的度之和）
+	Fiedler   float64    // 二分所用特征向量对应的L的特征值（MinCut时为第2小，即Fiedler value）
+}
+
+func NewSpecBisection(sim mat.Symmetric) *SpecBisection {
 	return &SpecBisection{
 		Similarities: sim,
 		MinCut:       true,
 		CutType:      RatioCut,
+		Solver:       DenseSolver,
+		MaxPasses:    10,
 	}
 }
 
-func (c *SpecBisection) Fit(X mat.Symmetric) error {
-	return c.partialFit(X)
+func (c *SpecBisection) Fit(X mat.Matrix) error {
+	return c.FitContext(context.Background(), X)
+}
+
+// FitContext 同Fit，在特征分解与balance的每次移动之间检查ctx，取消时返回ctx.Err()
+func (c *SpecBisection) FitContext(ctx context.Context, X mat.Matrix) error {
+	sim, err := asSymmetric(X)
+	if err != nil {
+		return err
+	}
+	if err = c.check(sim); err != nil {
+		return err
+	}
+	return c.partialFit(ctx, sim)
 }
 
 func (c *SpecBisection) HasFitted() bool { return c.done }
 
-func (c *SpecBisection) check() {
+// FitPredict 训练并返回[]int形式的二分结果（true为1，false为0）
+func (c *SpecBisection) FitPredict(X mat.Matrix) ([]int, error) {
+	if err := c.Fit(X); err != nil {
+		return nil, err
+	}
+	return utils.BoolsToInts(c.Labels()), nil
+}
+
+// Predict X的每一行为新样本与训练样本之间的相似度，新样本归入平均相似度更大的一侧
+func (c *SpecBisection) Predict(X mat.Matrix) []int {
+	return predictBySimilarity(X, utils.BoolsToInts(c.Labels()), 2)
+}
+
+func (c *SpecBisection) NumClusters() int { return 2 }
+
+func (c *SpecBisection) check(sim mat.Symmetric) error {
+	if sim.Symmetric() < 2 {
+		return ErrEmptyInput
+	}
 	if c.CutType != RatioCut && c.CutType != NCut {
-		panic(ErrInvalidArgument)
+		return &ParamError{Field: "CutType", Value: c.CutType}
 	}
+	if c.Weights != nil && len(c.Weights) != sim.Symmetric() {
+		return &ParamError{Field: "len(Weights)", Value: len(c.Weights)}
+	}
+	for _, w := range c.Weights {
+		if !(w > 0) {
+			return &ParamError{Field: "Weights", Value: w}
+		}
+	}
+	if c.Imbalance < 0 {
+		return &ParamError{Field: "Imbalance", Value: c.Imbalance}
+	}
+	if c.Refine && c.MaxPasses < 1 {
+		return &ParamError{Field: "MaxPasses", Value: c.MaxPasses}
+	}
+	return nil
 }
 
-func (c *SpecBisection) partialFit(sim mat.Symmetric) error {
+func (c *SpecBisection) partialFit(ctx context.Context, sim mat.Symmetric) (err error) {
 	var (
-		fac  *SpecFactorize
-		vec  *mat.VecDense
-		tNum int // 标记为true的类中元素个数
+		fac *SpecFactorize
+		vec *mat.VecDense
 	)
 
 	switch c.CutType {
@@ -61,82 +128,175 @@ func (c *SpecBisection) partialFit(sim mat.Symmetric) error {
 	case NCut:
 		fac = NewSpecFactorize(c.Verbose, true)
 	default:
-		panic(ErrInvalidArgument)
+		return &ParamError{Field: "CutType", Value: c.CutType}
 	}
-	if err := fac.Fit(sim); err != nil {
+	k := utils.If(c.MinCut, 2, -2).(int)
+	fac.Solver, fac.NEigen = c.Solver, k
+	if err = fac.partialFit(ctx, factorizeInput(sim, c.Solver)); err != nil {
 		return err
 	}
 
-	k := utils.If(c.MinCut, 2, -2).(int)
 	vec = fac.SmallNthEigenVector(k)
+	fiedler := fac.SmallNthEigenValue(k)
 
 	c.labels = make([]bool, vec.Len())
 	for i := 0; i < len(c.labels); i++ {
 		if vec.AtVec(i) > 0 {
 			c.labels[i] = true
-			tNum++
-			continue
 		}
 	}
-	c.major = utils.If(tNum >= vec.Len()-tNum, true, false).(bool)
+	c.initial = NewCutReport(sim, c.labels, c.Weights)
+	c.initial.Fiedler = fiedler
+	c.final = c.initial
 
 	if c.Strict {
-		c.major = c.balance(tNum, true)
+		if err = c.balance(ctx, sim); err != nil {
+			return err
+		}
+	}
+	if c.Refine {
+		if err = c.refine(ctx, sim); err != nil {
+			return err
+		}
 	}
+	if c.Strict || c.Refine {
+		c.final = NewCutReport(sim, c.labels, c.Weights)
+		c.final.Fiedler = fiedler
+		if c.Verbose {
+			log.Printf("[Bisection] RatioCut: %f -> %f, NCut: %f -> %f\n",
+				c.initial.RatioCut, c.final.RatioCut, c.initial.NCut, c.final.NCut)
+		}
+	}
+	c.major = c.final.Weights[1] >= c.final.Weights[0]
 
 	c.done = true
 	return nil
 }
 
-func (c *SpecBisection) balance(num int, class bool) bool {
+// balance 不断把较重一侧中移动代价最小（max-cut时为收益最大）的点移到另一侧，
+// 直到两侧的权重都不超过balanceLimit(Imbalance)
+func (c *SpecBisection) balance(ctx context.Context, sim mat.Symmetric) error {
 	var (
-		n        = len(c.labels)
-		another  = n - num
-		majority = utils.If(num > another, class, !class).(bool)
-		cnt      = utils.If(majority, (num-another)/2, (another-num)/2).(int)
+		n      = len(c.labels)
+		limit  = c.balanceLimit(c.Imbalance)
+		weight [2]float64
+		side   = func(b bool) int { return utils.If(b, 1, 0).(int) }
 	)
-
-	if cnt == 0 {
-		return majority
+	for i, label := range c.labels {
+		weight[side(label)] += c.weight(i)
 	}
-	var (
-		inc, dec float64
-		delta    float64
-		choose   int
-	)
 
-	for t := 0; t < cnt; t++ {
-		for i := 0; i < n; i++ { // 遍历majority
-			if c.labels[i] != majority {
+	for {
+		heavy := weight[1] > weight[0]
+		if weight[side(heavy)] <= limit {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var (
+			choose = -1
+			delta  = utils.If(c.MinCut, math.Inf(1), math.Inf(-1)).(float64)
+		)
+		for i := 0; i < n; i++ { // 遍历较重的一侧
+			if c.labels[i] != heavy {
 				continue
 			}
-			inc, dec = 0, 0
-			delta = utils.If(c.MinCut, math.Inf(1), math.Inf(-1)).(float64)
-			for j := 0; j < n; j++ {
+			var inc, dec float64
+			matrix.DoRowNonZero(sim, i, func(j int, v float64) {
 				if i == j {
-					continue
+					return
 				}
-				if c.labels[j] == majority {
-					inc += c.Similarities.At(i, j)
+				if c.labels[j] == heavy {
+					inc += v
 				} else {
-					dec += c.Similarities.At(i, j)
-				}
-			}
-			if c.MinCut {
-				if inc-dec < delta {
-					delta = inc - dec
-					choose = i
-				}
-			} else {
-				if inc-dec > delta {
-					delta = inc - dec
-					choose = i
+					dec += v
 				}
+			})
+			if (c.MinCut && inc-dec < delta) || (!c.MinCut && inc-dec > delta) {
+				delta, choose = inc-dec, i
 			}
 		}
-		c.labels[choose] = !majority
+		c.labels[choose] = !heavy
+		weight[side(heavy)] -= c.weight(choose)
+		weight[side(!heavy)] += c.weight(choose)
+	}
+}
+
+// balanceLimit 每一侧权重的上限 max((1+imbalance)*W/2, (W+w_max)/2)
+// 后一项保证总能满足，imbalance为0且没有节点权重时即为⌈n/2⌉
+func (c *SpecBisection) balanceLimit(imbalance float64) float64 {
+	var total, max float64
+	for i := range c.labels {
+		total += c.weight(i)
+		max = math.Max(max, c.weight(i))
+	}
+	return math.Max((1+imbalance)*total/2, (total+max)/2)
+}
+
+func (c *SpecBisection) weight(i int) float64 {
+	if c.Weights == nil {
+		return 1
+	}
+	return c.Weights[i]
+}
+
+func (c *SpecBisection) Labels() []bool {
+	utils.Assert(c.HasFitted(), ErrFitHasNotDone)
+	return c.labels
+}
+
+// Major 权重（未设置Weights时为样本数）较大的是哪一侧
+func (c *SpecBisection) Major() bool {
+	utils.Assert(c.HasFitted(), ErrFitHasNotDone)
+	return c.major
+}
+
+// Report 二分的诊断信息：initial为按特征向量符号二分（balance与FM之前）的结果，final为最终结果
+// 非Strict且非Refine时二者相同
+func (c *SpecBisection) Report() (initial, final *CutReport) {
+	utils.Assert(c.HasFitted(), ErrFitHasNotDone)
+	return c.initial, c.final
+}
+
+// NewCutReport 计算二分labels的诊断信息（Fiedler为0），weights为nil时每个节点的权重为1
+// 某一侧为空（或体积为0）时，对应的RatioCut（或NCut）为+Inf
+func NewCutReport(sim mat.Symmetric, labels []bool, weights []float64) *CutReport {
+	var (
+		r    = &CutReport{}
+		side = func(b bool) int { return utils.If(b, 1, 0).(int) }
+	)
+	for i, label := range labels {
+		w := 1.0
+		if weights != nil {
+			w = weights[i]
+		}
+		r.Sizes[side(label)]++
+		r.Weights[side(label)] += w
+		matrix.DoRowNonZero(sim, i, func(j int, v float64) {
+			r.Volumes[side(label)] += v
+			if labels[j] != label && i < j {
+				r.CutWeight += v
+			}
+		})
 	}
-	return majority
+	r.RatioCut = cutObjective(r.CutWeight, float64(r.Sizes[0]), float64(r.Sizes[1]))
+	r.NCut = cutObjective(r.CutWeight, r.Volumes[0], r.Volumes[1])
+	return r
 }
 
-func (c *SpecBisection) Labels() []bool { return c.labels }
+// CutValue 二分labels的cut值，cutType为ratio_cut或n_cut
+func CutValue(sim mat.Symmetric, labels []bool, cutType string) float64 {
+	r := NewCutReport(sim, labels, nil)
+	if cutType == NCut {
+		return r.NCut
+	}
+	return r.RatioCut
+}
+
+func cutObjective(cut, a, b float64) float64 {
+	if a <= 0 || b <= 0 {
+		return math.Inf(1)
+	}
+	return cut/a + cut/b
+}
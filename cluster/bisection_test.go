@@ -6,8 +6,10 @@
 package cluster
 
 import (
+	"errors"
 	"fmt"
 	"github.com/yinyajun/golearn/utils"
+	"math"
 	"math/rand"
 	"testing"
 
@@ -32,10 +34,13 @@ func TestSpecBisection(t *testing.T) {
 		NGoroutines: 8,
 	}
 
-	sim := d.SelfCartesian(utils.Range(0, 30, 1))
+	sim, err := d.SelfCartesian(utils.Range(0, 30, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
 	b := NewSpecBisection(sim)
 	b.Strict = true
-	err := b.Fit(sim)
+	err = b.Fit(sim)
 	fmt.Println(err)
 	fmt.Println(b.Labels())
 	fmt.Println(b.major)
@@ -57,7 +62,7 @@ func BenchmarkSpecBisection(b *testing.B) {
 		Filters:     nil,
 		NGoroutines: 16,
 	}
-	sim := d.SelfCartesian(utils.Range(0, 50, 1))
+	sim, _ := d.SelfCartesian(utils.Range(0, 50, 1))
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		s := NewSpecBisection(sim)
@@ -69,3 +74,204 @@ func BenchmarkSpecBisection(b *testing.B) {
 	}
 
 }
+
+func TestSpecBisection_Predict(t *testing.T) {
+	// 两个互不相连的团
+	sim := mat.NewSymDense(6, nil)
+	for _, group := range [][]int{{0, 1, 2}, {3, 4, 5}} {
+		for _, i := range group {
+			for _, j := range group {
+				if i != j {
+					sim.SetSym(i, j, 1)
+				}
+			}
+		}
+	}
+	var c Clusterer = NewSpecBisection(sim)
+	labels, err := c.FitPredict(sim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels[0] != labels[1] || labels[0] != labels[2] || labels[0] == labels[3] {
+		t.Fatalf("unexpected bisection %v", labels)
+	}
+	predicted := c.Predict(sim)
+	for i := range labels {
+		if labels[i] != predicted[i] {
+			t.Fatalf("unexpected prediction %v", predicted)
+		}
+	}
+}
+
+func TestSpecBisection_Sparse(t *testing.T) {
+	// 两条链0-1-2-3-4与5-6-7-8-9，中间由一条弱边4-5相连
+	var (
+		rows, cols []int
+		vals       []float64
+	)
+	for i := 0; i < 9; i++ {
+		rows, cols = append(rows, i), append(cols, i+1)
+		vals = append(vals, utils.If(i == 4, 0.01, 1.0).(float64))
+	}
+	sim := matrix.NewSymCSR(10, rows, cols, vals)
+
+	for _, norm := range []string{UnnormalizedLaplacian, SymmetricLaplacian} {
+		L := laplacian(sim, norm)
+		if _, ok := L.(*matrix.SymCSR); !ok {
+			t.Fatalf("expected sparse laplacian, got %T", L)
+		}
+		dense := laplacian(sim.ToSymDense(), norm)
+		if !mat.EqualApprox(L, dense, 1e-12) {
+			t.Fatalf("sparse laplacian (norm=%v) mismatch", norm)
+		}
+	}
+
+	b := NewSpecBisection(sim)
+	labels, err := b.FitPredict(sim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if (labels[i] == labels[0]) != (i < 5) {
+			t.Fatalf("unexpected bisection %v", labels)
+		}
+	}
+}
+
+func TestSpecBisection_Report(t *testing.T) {
+	// 大小为3和5的两个团，由一条权重0.1的弱边相连
+	sim := mat.NewSymDense(8, nil)
+	for _, group := range [][]int{{0, 1, 2}, {3, 4, 5, 6, 7}} {
+		for _, i := range group {
+			for _, j := range group {
+				if i < j {
+					sim.SetSym(i, j, 1)
+				}
+			}
+		}
+	}
+	sim.SetSym(2, 3, 0.1)
+
+	b := NewSpecBisection(sim)
+	if err := b.Fit(sim); err != nil {
+		t.Fatal(err)
+	}
+	initial, final := b.Report()
+	if initial != final || math.Abs(initial.CutWeight-0.1) > 1e-12 {
+		t.Fatalf("unexpected report %+v", initial)
+	}
+	if math.Abs(initial.RatioCut-(0.1/3+0.1/5)) > 1e-12 || initial.Fiedler <= 0 {
+		t.Errorf("unexpected report %+v", initial)
+	}
+	small := utils.If(initial.Sizes[0] == 3, 0, 1).(int)
+	if initial.Sizes[1-small] != 5 || initial.Volumes[small] != 6.1 || b.Major() != (small == 0) {
+		t.Errorf("unexpected report %+v, major %v", initial, b.Major())
+	}
+
+	// 严格二分后为4:4，cut变大
+	b.Strict = true
+	if err := b.Fit(sim); err != nil {
+		t.Fatal(err)
+	}
+	initial, final = b.Report()
+	if final.Sizes != [2]int{4, 4} || final.CutWeight <= initial.CutWeight || final.Fiedler != initial.Fiedler {
+		t.Errorf("unexpected reports %+v -> %+v", initial, final)
+	}
+}
+
+func TestSpecBisection_Refine(t *testing.T) {
+	// 三个团串成一条链：按Fiedler向量的符号二分会切开中间的团，FM应当把它修正为只切一条弱边
+	sim, _ := cliques(3, 6, 0.05)
+	b := NewSpecBisection(sim)
+	b.Refine, b.Imbalance = true, 0.34 // 每一侧不超过12个
+	if err := b.Fit(sim); err != nil {
+		t.Fatal(err)
+	}
+	initial, final := b.Report()
+	if math.Abs(final.CutWeight-0.05) > 1e-12 || final.CutWeight > initial.CutWeight {
+		t.Errorf("unexpected reports %+v -> %+v", initial, final)
+	}
+
+	// 随机图上严格二分：FM之后仍然平衡，且cut不会变大
+	r := rand.New(rand.NewSource(3))
+	dense := mat.NewSymDense(60, nil)
+	for i := 0; i < 60; i++ {
+		for j := i + 1; j < 60; j++ {
+			if r.Float64() < utils.If(i/30 == j/30, 0.3, 0.1).(float64) {
+				dense.SetSym(i, j, r.Float64())
+			}
+		}
+	}
+	balanced := NewSpecBisection(dense)
+	balanced.Strict = true
+	if err := balanced.Fit(dense); err != nil {
+		t.Fatal(err)
+	}
+	_, before := balanced.Report()
+	balanced.Refine = true
+	if err := balanced.Fit(dense); err != nil {
+		t.Fatal(err)
+	}
+	_, after := balanced.Report()
+	if after.Sizes != [2]int{30, 30} || after.CutWeight > before.CutWeight+1e-12 {
+		t.Errorf("unexpected reports %+v -> %+v", before, after)
+	}
+}
+
+func TestSpecBisection_StrictDefault(t *testing.T) {
+	// 大小为20与n-20的两个簇：默认Imbalance下Strict严格对半（奇数时较大一侧为⌈n/2⌉）
+	r := rand.New(rand.NewSource(7))
+	for _, n := range []int{30, 31} {
+		dense := mat.NewSymDense(n, nil)
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				if r.Float64() < utils.If(i < 20 == (j < 20), 0.5, 0.05).(float64) {
+					dense.SetSym(i, j, r.Float64())
+				}
+			}
+		}
+		b := NewSpecBisection(nil) // balance使用Fit传入的相似度矩阵
+		b.Strict = true
+		if err := b.Fit(dense); err != nil {
+			t.Fatal(err)
+		}
+		initial, final := b.Report()
+		if initial.Sizes == final.Sizes {
+			t.Fatalf("n=%d: expected balance to move nodes, got %+v", n, initial)
+		}
+		if big := utils.If(final.Sizes[0] > final.Sizes[1], final.Sizes[0], final.Sizes[1]).(int); big != (n+1)/2 {
+			t.Errorf("n=%d: expected an exact split, got %v", n, final.Sizes)
+		}
+	}
+}
+
+func TestSpecBisection_Weights(t *testing.T) {
+	// 两个大小为4的团，第一个团中每个节点的权重为3，第二个团为1
+	sim, _ := cliques(2, 4, 0.1)
+	weights := []float64{3, 3, 3, 3, 1, 1, 1, 1}
+
+	b := NewSpecBisection(sim)
+	b.Strict, b.Weights, b.Imbalance = true, weights, 0.1
+	if err := b.Fit(sim); err != nil {
+		t.Fatal(err)
+	}
+	// 每一侧的权重不超过max(1.1*16/2, (16+3)/2) = 9.5，只需从重的一侧移出一个节点
+	_, final := b.Report()
+	if final.Weights[0] > 9.5 || final.Weights[1] > 9.5 || final.Sizes != [2]int{3, 5} && final.Sizes != [2]int{5, 3} {
+		t.Errorf("unexpected report %+v", final)
+	}
+
+	// 容忍度足够大时不需要移动
+	b.Imbalance = 0.6
+	if err := b.Fit(sim); err != nil {
+		t.Fatal(err)
+	}
+	if initial, final := b.Report(); final.CutWeight != initial.CutWeight || math.Abs(final.CutWeight-0.1) > 1e-12 {
+		t.Errorf("unexpected reports %+v -> %+v", initial, final)
+	}
+
+	b.Weights = weights[:3]
+	if err := b.Fit(sim); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument, got %v", err)
+	}
+}
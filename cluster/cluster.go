@@ -0,0 +1,81 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/12 16:20
+ */
+
+package cluster
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/yinyajun/golearn/matrix"
+	"github.com/yinyajun/golearn/utils"
+	"gonum.org/v1/gonum/mat"
+)
+
+// Clusterer 聚类器的通用接口
+// KMeans系列的X为数据矩阵（每行一个样本）；谱方法的X为相似度矩阵，
+// 谱方法Predict时X的每一行为新样本与训练样本之间的相似度
+type Clusterer interface {
+	Fit(X mat.Matrix) error                 // 训练
+	FitPredict(X mat.Matrix) ([]int, error) // 训练并返回训练样本的类别
+	Predict(X mat.Matrix) []int             // 预测X每一行的类别
+	NumClusters() int                       // 类别数
+	HasFitted() bool                        // 是否已训练
+}
+
+var (
+	_ Clusterer = (*KMeans)(nil)
+	_ Clusterer = (*MiniBatchKMeans)(nil)
+	_ Clusterer = (*SpecClustering)(nil)
+	_ Clusterer = (*SpecBisection)(nil)
+	_ Clusterer = (*NystromClustering)(nil)
+	_ Clusterer = (*RecursiveBisection)(nil)
+)
+
+// asDense 将X转为*mat.Dense（X本身是*mat.Dense时不拷贝）
+func asDense(X mat.Matrix) *mat.Dense {
+	if d, ok := X.(*mat.Dense); ok {
+		return d
+	}
+	return mat.DenseCopyOf(X)
+}
+
+// asSymmetric 谱方法的输入必须是对称的相似度矩阵
+func asSymmetric(X mat.Matrix) (mat.Symmetric, error) {
+	if s, ok := X.(mat.Symmetric); ok {
+		return s, nil
+	}
+	return nil, &ParamError{Field: "X", Value: fmt.Sprintf("%T is not mat.Symmetric", X)}
+}
+
+// predictBySimilarity 谱方法的样本外预测
+// sim的每一行为新样本与训练样本的相似度，新样本归入平均相似度最大的类
+func predictBySimilarity(sim mat.Matrix, labels []int, nClusters int) []int {
+	r, c := sim.Dims()
+	utils.Assert(c == len(labels), &ParamError{Field: "X.Cols", Value: c})
+
+	var (
+		res   = make([]int, r)
+		sums  = make([]float64, nClusters)
+		sizes = make([]int, nClusters)
+	)
+	for _, label := range labels {
+		sizes[label]++
+	}
+	for i := 0; i < r; i++ {
+		for k := range sums {
+			sums[k] = 0
+		}
+		matrix.DoRowNonZero(sim, i, func(j int, v float64) { sums[labels[j]] += v })
+		best, bestSim := 0, math.Inf(-1)
+		for k := 0; k < nClusters; k++ {
+			if sizes[k] > 0 && sums[k]/float64(sizes[k]) > bestSim {
+				best, bestSim = k, sums[k]/float64(sizes[k])
+			}
+		}
+		res[i] = best
+	}
+	return res
+}
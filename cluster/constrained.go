@@ -0,0 +1,123 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/31 15:20
+ */
+
+package cluster
+
+import (
+	"context"
+	"log"
+
+	"github.com/yinyajun/golearn/graph"
+	"github.com/yinyajun/golearn/utils"
+	"gonum.org/v1/gonum/mat"
+)
+
+// singleConstrained 类大小受限的kmeans（Bradley, Bennett & Demiriz, 2000）
+// 与singleFull相同地交替分配与更新中心，但分配步骤是一个最小费用流问题：
+// 每个点为1单位流量，点到类的费用为其到中心的距离平方，第c类的流量在[MinSize, MaxSize]之间
+func (m *KMeans) singleConstrained(ctx context.Context, points *mat.Dense, seed int64) error {
+	m.initCenters(points, seed)
+	for iter := 0; iter < m.MaxIter && !m.unchanged; iter++ {
+		if err := m.assignConstrained(ctx, points); err != nil {
+			return err
+		}
+		m.update(points)
+		if m.Verbose {
+			log.Printf("[Epoch %d] Cost: %f\n", iter, m.cost)
+		}
+	}
+	return nil
+}
+
+// assignConstrained 满足类大小约束、距离平方之和最小的分配
+// 先把每个点分配到最近的中心（不考虑约束时的最优分配），再用最小费用流调整：
+// 源点 -> 类c（容量为最近中心是c的点数）；类c -> 点i（i最近的中心是c，容量1）-> 类c'（容量1，
+// 费用为i改为分配到c'增加的距离平方，非负）；类 -> 汇点（容量lo），超出lo的部分经过一个中间点
+// 类 -> A（容量hi-lo）-> 汇点（容量n-k·lo）。流量为n时每个类的大小都在[lo, hi]之间。
+// 初始分配没有负费用的调整环，所以在它之上的连续最短路得到的是最优分配；大部分流量走费用为0的路径，
+// 只有需要调整的点才需要额外的最短路
+func (m *KMeans) assignConstrained(ctx context.Context, points *mat.Dense) error {
+	var (
+		n, k    = m.nSamples(points), m.NClusters
+		lo, hi  = m.sizeBounds(n)
+		cost    = mat.NewDense(n, k, nil)
+		nearest = make([]int, n)
+		counts  = make([]int, k)
+		net     = graph.NewFlowNetwork(n + k + 3)
+		src     = n + k
+		sink    = n + k + 1
+		extra   = n + k + 2
+		moves   = make([]int, n*k)
+	)
+	m.parallel(n, func(from, to int) int {
+		for i := from; i < to; i++ {
+			if i%checkInterval == 0 && ctx.Err() != nil {
+				return 0
+			}
+			for c := 0; c < k; c++ {
+				cost.Set(i, c, utils.EuclideanSquare(points.RowView(i), m.centers.RowView(c)))
+				if cost.At(i, c) < cost.At(i, nearest[i]) {
+					nearest[i] = c
+				}
+			}
+		}
+		return 0
+	})
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for i := 0; i < n; i++ {
+		c := nearest[i]
+		counts[c]++
+		net.AddArc(n+c, i, 1, 0)
+		for t := 0; t < k; t++ {
+			if t != c {
+				moves[i*k+t] = net.AddArc(i, n+t, 1, cost.At(i, t)-cost.At(i, c))
+			}
+		}
+	}
+	for c := 0; c < k; c++ {
+		net.AddArc(src, n+c, counts[c], 0)
+		net.AddArc(n+c, sink, lo, 0)
+		net.AddArc(n+c, extra, hi-lo, 0)
+	}
+	net.AddArc(extra, sink, n-k*lo, 0)
+	flow, _, err := net.MinCostMaxFlow(src, sink)
+	if err != nil {
+		return err
+	}
+	if flow != n { // checkParams已经保证约束可行，不应发生
+		return ErrInfeasible
+	}
+
+	changed := 0
+	for i := 0; i < n; i++ {
+		label := nearest[i]
+		for t := 0; t < k; t++ {
+			if t != nearest[i] && net.Flow(moves[i*k+t]) > 0 {
+				label = t
+			}
+		}
+		if m.labels[i] != label {
+			m.labels[i] = label
+			changed++
+		}
+	}
+	m.unchanged = changed == 0
+	return nil
+}
+
+// sizeBounds 类大小的上下界：MinSize为0时按1处理（避免出现空的类），MaxSize为0时不限制
+func (m *KMeans) sizeBounds(n int) (lo, hi int) {
+	lo, hi = m.MinSize, m.MaxSize
+	if lo < 1 {
+		lo = 1
+	}
+	if hi <= 0 {
+		hi = n
+	}
+	return
+}
@@ -0,0 +1,220 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/9 21:05
+ */
+
+package cluster
+
+import (
+	"context"
+	"log"
+	"math"
+
+	"github.com/yinyajun/golearn/utils"
+	"gonum.org/v1/gonum/mat"
+)
+
+// elkanBounds Elkan算法中每个点维护的距离边界
+type elkanBounds struct {
+	upper      []float64     // 点到所属中心距离的上界
+	lower      *mat.Dense    // 点到各个中心距离的下界
+	stale      []bool        // upper是否因中心移动而不再是精确距离
+	centerDist *mat.SymDense // 中心两两之间的距离
+	half       []float64     // 中心到最近的其他中心距离的一半
+	shift      []float64     // 本轮中心的移动距离
+}
+
+// singleElkan 利用三角不等式加速的kmeans（Elkan, 2003）
+// 与singleFull的迭代过程完全一致（相同seed下得到相同的labels和cost），
+// 但通过上下界跳过绝大多数不可能改变归属的距离计算
+func (m *KMeans) singleElkan(ctx context.Context, points *mat.Dense, seed int64) error {
+	m.initCenters(points, seed)
+
+	var (
+		n = m.nSamples(points)
+		b = &elkanBounds{
+			upper:      make([]float64, n),
+			lower:      mat.NewDense(n, m.NClusters, nil),
+			stale:      make([]bool, n),
+			centerDist: mat.NewSymDense(m.NClusters, nil),
+			half:       make([]float64, m.NClusters),
+			shift:      make([]float64, m.NClusters),
+		}
+		changed int
+	)
+
+	for iter := 0; iter < m.MaxIter && !m.unchanged; iter++ {
+		m.elkanCenterDist(b)
+		if iter == 0 {
+			changed = m.parallel(n, func(lo, hi int) int { return m.elkanInit(ctx, points, b, lo, hi) })
+		} else {
+			changed = m.parallel(n, func(lo, hi int) int { return m.elkanAssign(ctx, points, b, lo, hi) })
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		m.unchanged = changed == 0
+		m.elkanUpdate(points, b)
+		if m.Verbose {
+			m.cost = m.inertia(points)
+			log.Printf("[Epoch %d] Cost: %f, Changed: %d\n", iter, m.cost, changed)
+		}
+	}
+	m.cost = m.inertia(points)
+	return nil
+}
+
+// elkanCenterDist 计算中心两两之间的距离，以及每个中心到最近其他中心距离的一半
+func (m *KMeans) elkanCenterDist(b *elkanBounds) {
+	for i := 0; i < m.NClusters; i++ {
+		b.half[i] = math.Inf(1)
+	}
+	for i := 0; i < m.NClusters; i++ {
+		for j := i + 1; j < m.NClusters; j++ {
+			d := utils.Euclidean(m.centers.RowView(i), m.centers.RowView(j))
+			b.centerDist.SetSym(i, j, d)
+			b.half[i] = math.Min(b.half[i], d/2)
+			b.half[j] = math.Min(b.half[j], d/2)
+		}
+	}
+}
+
+// elkanInit 第一轮分配：d(x,c) <= d(c,c')/2 时，无需计算d(x,c')
+func (m *KMeans) elkanInit(ctx context.Context, points *mat.Dense, b *elkanBounds, lo, hi int) (changed int) {
+	var cluster int
+	for i := lo; i < hi; i++ {
+		if i%checkInterval == 0 && ctx.Err() != nil {
+			return
+		}
+		x := points.RowView(i)
+		cluster = 0
+		b.upper[i] = utils.Euclidean(x, m.centers.RowView(0))
+		b.lower.Set(i, 0, b.upper[i])
+		for k := 1; k < m.NClusters; k++ {
+			if b.upper[i] <= b.centerDist.At(cluster, k)/2 {
+				continue
+			}
+			d := utils.Euclidean(x, m.centers.RowView(k))
+			b.lower.Set(i, k, d)
+			if d < b.upper[i] {
+				cluster, b.upper[i] = k, d
+			}
+		}
+		if m.labels[i] != cluster {
+			m.labels[i] = cluster
+			changed++
+		}
+	}
+	return
+}
+
+// elkanAssign 之后各轮的分配，利用上下界剪枝
+func (m *KMeans) elkanAssign(ctx context.Context, points *mat.Dense, b *elkanBounds, lo, hi int) (changed int) {
+	var cluster int
+	for i := lo; i < hi; i++ {
+		if i%checkInterval == 0 && ctx.Err() != nil {
+			return
+		}
+		cluster = m.labels[i]
+		if b.upper[i] <= b.half[cluster] {
+			continue
+		}
+		x := points.RowView(i)
+		for k := 0; k < m.NClusters; k++ {
+			if k == cluster || b.upper[i] <= b.lower.At(i, k) ||
+				b.upper[i] <= b.centerDist.At(cluster, k)/2 {
+				continue
+			}
+			if b.stale[i] { // 收紧上界后再判断一次
+				b.upper[i] = utils.Euclidean(x, m.centers.RowView(cluster))
+				b.lower.Set(i, cluster, b.upper[i])
+				b.stale[i] = false
+				if b.upper[i] <= b.lower.At(i, k) || b.upper[i] <= b.centerDist.At(cluster, k)/2 {
+					continue
+				}
+			}
+			d := utils.Euclidean(x, m.centers.RowView(k))
+			b.lower.Set(i, k, d)
+			if d < b.upper[i] {
+				cluster, b.upper[i] = k, d
+			}
+		}
+		if m.labels[i] != cluster {
+			m.labels[i] = cluster
+			changed++
+		}
+	}
+	return
+}
+
+// elkanUpdate 更新聚类中心，并根据中心的移动距离放松上下界
+func (m *KMeans) elkanUpdate(points *mat.Dense, b *elkanBounds) {
+	var (
+		n, nFeatures = points.Dims()
+		sums         = mat.NewDense(m.NClusters, nFeatures, nil)
+		cnt          = make([]int, m.NClusters)
+	)
+	for i := 0; i < n; i++ {
+		row := sums.RowView(m.labels[i]).(*mat.VecDense)
+		row.AddVec(row, points.RowView(i))
+		cnt[m.labels[i]]++
+	}
+	for k := 0; k < m.NClusters; k++ {
+		if cnt[k] == 0 { // 空簇保持原中心
+			b.shift[k] = 0
+			continue
+		}
+		centroid := sums.RowView(k).(*mat.VecDense)
+		centroid.ScaleVec(1/float64(cnt[k]), centroid)
+		b.shift[k] = utils.Euclidean(centroid, m.centers.RowView(k))
+		m.centers.SetRow(k, centroid.RawVector().Data)
+	}
+
+	for i := 0; i < n; i++ {
+		for k := 0; k < m.NClusters; k++ {
+			b.lower.Set(i, k, math.Max(b.lower.At(i, k)-b.shift[k], 0))
+		}
+		if b.shift[m.labels[i]] > 0 {
+			b.upper[i] += b.shift[m.labels[i]]
+			b.stale[i] = true
+		}
+	}
+}
+
+// inertia 计算所有点到所属聚类中心的距离平方和
+func (m *KMeans) inertia(points *mat.Dense) float64 {
+	costs := make([]float64, m.NClusters)
+	for i, class := range m.labels {
+		costs[class] += utils.EuclideanSquare(m.centers.RowView(class), points.RowView(i))
+	}
+	var cost float64
+	for _, c := range costs {
+		cost += c
+	}
+	return cost
+}
+
+// parallel 将[0,n)均分为NGoroutines段并发执行fn，返回各段结果之和
+func (m *KMeans) parallel(n int, fn func(lo, hi int) int) int {
+	var (
+		step    = (n + m.NGoroutines - 1) / m.NGoroutines
+		results = make(chan int)
+		parts   int
+		sum     int
+	)
+	for lo := 0; lo < n; lo += step {
+		hi := lo + step
+		if hi > n {
+			hi = n
+		}
+		parts++
+		go func(lo, hi int) {
+			results <- fn(lo, hi)
+		}(lo, hi)
+	}
+	for i := 0; i < parts; i++ {
+		sum += <-results
+	}
+	close(results)
+	return sum
+}
@@ -0,0 +1,148 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/26 15:12
+ */
+
+package cluster
+
+import (
+	"context"
+	"math"
+	"math/rand"
+
+	"github.com/yinyajun/golearn/utils"
+	"gonum.org/v1/gonum/mat"
+)
+
+// Transformer 特征变换器的通用接口，X的含义同Clusterer
+type Transformer interface {
+	Fit(X mat.Matrix) error                        // 训练
+	FitTransform(X mat.Matrix) (*mat.Dense, error) // 训练并返回训练样本变换后的特征（每行一个样本）
+	HasFitted() bool                               // 是否已训练
+}
+
+var _ Transformer = (*SpectralEmbedding)(nil)
+
+// SpectralEmbedding 谱嵌入（Laplacian Eigenmaps, Belkin & Niyogi, 2003）
+// 以拉普拉斯矩阵最小的NComponents个特征向量作为每个样本的低维坐标，可用于可视化或作为下游模型的特征。
+// 默认使用random_walk，即广义特征问题 L v = λ D v 的解；第j列对应第j小的特征值，
+// 每列的符号固定为第一个不可忽略（绝对值大于最大绝对值的1e-8倍）的元素为正，结果是确定的
+type SpectralEmbedding struct {
+	NComponents        int         // 嵌入维度d
+	Laplacian          string      // 拉普拉斯矩阵类型："unnormalized", "symmetric", "random_walk"
+	DropFirst          bool        // 丢弃最小特征值对应的平凡特征向量（连通图上为常数向量，symmetric时为D^(1/2)·1）
+	ScaleByEigenvalues bool        // 每列乘以1/sqrt(λ)（commute time嵌入），λ接近0的列不缩放
+	Solver             string      // 特征分解方法："dense"或"lanczos"
+	Verbose            bool        // 冗余模式
+	RandomState        rand.Source // Lanczos初始向量的随机源（nil时以当前时间为种子）
+	embedding          *mat.Dense
+	eigenValues        []float64
+	done               bool
+}
+
+func NewSpectralEmbedding(NComponents int) *SpectralEmbedding {
+	return &SpectralEmbedding{
+		NComponents: NComponents,
+		Laplacian:   RandomWalkLaplacian,
+		DropFirst:   true,
+		Solver:      DenseSolver,
+	}
+}
+
+func (e *SpectralEmbedding) Fit(X mat.Matrix) error {
+	return e.FitContext(context.Background(), X)
+}
+
+// FitContext 同Fit，在特征分解时检查ctx，取消时返回ctx.Err()
+func (e *SpectralEmbedding) FitContext(ctx context.Context, X mat.Matrix) error {
+	sim, err := asSymmetric(X)
+	if err != nil {
+		return err
+	}
+	if err = e.check(sim); err != nil {
+		return err
+	}
+	return e.partialFit(ctx, sim)
+}
+
+func (e *SpectralEmbedding) FitTransform(X mat.Matrix) (*mat.Dense, error) {
+	if err := e.Fit(X); err != nil {
+		return nil, err
+	}
+	return e.Embedding(), nil
+}
+
+func (e *SpectralEmbedding) check(sim mat.Symmetric) error {
+	n := sim.Symmetric()
+	if n == 0 {
+		return ErrEmptyInput
+	}
+	if e.NComponents < 1 || e.nEigen() > n {
+		return &ParamError{Field: "NComponents", Value: e.NComponents}
+	}
+	return nil
+}
+
+// nEigen 需要求解的特征向量个数
+func (e *SpectralEmbedding) nEigen() int {
+	return e.NComponents + utils.If(e.DropFirst, 1, 0).(int)
+}
+
+func (e *SpectralEmbedding) partialFit(ctx context.Context, sim mat.Symmetric) error {
+	var (
+		n     = sim.Symmetric()
+		k     = e.nEigen()
+		first = k - e.NComponents
+		fac   = NewSpecFactorize(e.Verbose, false)
+	)
+	fac.Laplacian, fac.Solver, fac.NEigen, fac.RandomState = e.Laplacian, e.Solver, k, e.RandomState
+	if err := fac.partialFit(ctx, factorizeInput(sim, e.Solver)); err != nil {
+		return err
+	}
+
+	e.embedding = mat.NewDense(n, e.NComponents, nil)
+	e.eigenValues = make([]float64, e.NComponents)
+	for j := 0; j < e.NComponents; j++ {
+		var (
+			v      = fac.SmallNthEigenVector(first + j + 1)
+			lambda = fac.SmallNthEigenValue(first + j + 1)
+			scale  = 1.0
+		)
+		if e.ScaleByEigenvalues && lambda > 1e-10 {
+			scale = 1 / math.Sqrt(lambda)
+		}
+		if v.AtVec(leadingIndex(v)) < 0 {
+			scale = -scale
+		}
+		col := e.embedding.ColView(j).(*mat.VecDense)
+		col.ScaleVec(scale, v)
+		e.eigenValues[j] = lambda
+	}
+	e.done = true
+	return nil
+}
+
+func (e *SpectralEmbedding) HasFitted() bool { return e.done }
+
+// Embedding 训练样本的谱嵌入（n×NComponents）
+func (e *SpectralEmbedding) Embedding() *mat.Dense {
+	utils.Assert(e.HasFitted(), ErrFitHasNotDone)
+	return e.embedding
+}
+
+// EigenValues 嵌入每一列对应的拉普拉斯矩阵特征值（升序）
+func (e *SpectralEmbedding) EigenValues() []float64 {
+	utils.Assert(e.HasFitted(), ErrFitHasNotDone)
+	return e.eigenValues
+}
+
+// leadingIndex 第一个不可忽略的元素下标（按数值误差，绝对值最大的元素可能不唯一，不能用来固定符号）
+func leadingIndex(v mat.Vector) int {
+	tol := 1e-8 * mat.Norm(v, math.Inf(1))
+	for i := 0; i < v.Len(); i++ {
+		if math.Abs(v.AtVec(i)) > tol {
+			return i
+		}
+	}
+	return 0
+}
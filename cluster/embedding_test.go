@@ -0,0 +1,84 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/26 16:40
+ */
+
+package cluster
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestSpectralEmbedding(t *testing.T) {
+	sim, truth := cliques(2, 6, 0.1)
+
+	// 不丢弃时第一列为常数向量，特征值为0
+	e := NewSpectralEmbedding(2)
+	e.DropFirst = false
+	X, err := e.FitTransform(sim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, c := X.Dims(); r != 12 || c != 2 {
+		t.Fatalf("unexpected dims %d×%d", r, c)
+	}
+	if math.Abs(e.EigenValues()[0]) > 1e-10 {
+		t.Fatalf("expected trivial eigenvalue 0, got %v", e.EigenValues()[0])
+	}
+	for i := 1; i < 12; i++ {
+		if math.Abs(X.At(i, 0)-X.At(0, 0)) > 1e-10 {
+			t.Fatalf("expected constant first column, got %v", mat.Col(nil, 0, X))
+		}
+	}
+
+	// 丢弃后第一列（Fiedler向量）按符号区分两个团
+	e = NewSpectralEmbedding(1)
+	X, err = e.FitTransform(sim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, g := range truth {
+		if (X.At(i, 0) > 0) != (X.At(0, 0) > 0) == (g == truth[0]) {
+			t.Fatalf("unexpected embedding %v", mat.Col(nil, 0, X))
+		}
+	}
+
+	// Lanczos与dense的结果一致（符号已固定，测试图的特征值没有重根），缩放后每列乘以1/sqrt(λ)
+	ring := ringGraph(40, 2, 3)
+	for _, laplacian := range []string{UnnormalizedLaplacian, SymmetricLaplacian, RandomWalkLaplacian} {
+		dense := NewSpectralEmbedding(3)
+		dense.Laplacian = laplacian
+		want, err := dense.FitTransform(ring.ToSymDense())
+		if err != nil {
+			t.Fatal(err)
+		}
+		lanczos := NewSpectralEmbedding(3)
+		lanczos.Laplacian, lanczos.Solver, lanczos.ScaleByEigenvalues = laplacian, LanczosSolver, true
+		lanczos.RandomState = rand.NewSource(1)
+		got, err := lanczos.FitTransform(ring)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j, lambda := range dense.EigenValues() {
+			if math.Abs(lambda-lanczos.EigenValues()[j]) > 1e-8 {
+				t.Fatalf("%s: eigenvalue %d expected %v, got %v", laplacian, j, lambda, lanczos.EigenValues()[j])
+			}
+			for i := 0; i < 40; i++ {
+				if math.Abs(got.At(i, j)*math.Sqrt(lambda)-want.At(i, j)) > 1e-6 {
+					t.Fatalf("%s: column %d expected %v, got %v", laplacian, j, mat.Col(nil, j, want), mat.Col(nil, j, got))
+				}
+			}
+		}
+	}
+
+	if _, err := NewSpectralEmbedding(12).FitTransform(sim); err == nil {
+		t.Errorf("expected error for NComponents >= n when dropping the first")
+	}
+	if _, err := NewSpectralEmbedding(0).FitTransform(sim); err == nil {
+		t.Errorf("expected error for NComponents=0")
+	}
+}
@@ -5,17 +5,22 @@
 
 package cluster
 
-import "errors"
-
-const (
-	ErrIndexOutOfRange = "index out of range"
-	ErrInvalidArgument = "invalid argument"
-	ErrEmptyInput      = "empty input"
-	//ErrEigenFactorization = "eigen factorization fails"
+import (
+	"errors"
 
+	"github.com/yinyajun/golearn/utils"
 )
 
 var (
+	ErrIndexOutOfRange    = errors.New("index out of range")
+	ErrInvalidArgument    = utils.ErrInvalidArgument
+	ErrEmptyInput         = utils.ErrEmptyInput
 	ErrEigenFactorization = errors.New("eigen factorization fails")
+	ErrSVDFactorization   = errors.New("svd factorization fails")
+	ErrNotConverged       = errors.New("iteration does not converge")
+	ErrInfeasible         = errors.New("constraints are infeasible")
 	ErrFitHasNotDone      = errors.New("fit has not done")
 )
+
+// ParamError 参数不合法，errors.Is(err, ErrInvalidArgument)成立
+type ParamError = utils.ParamError
@@ -6,50 +6,173 @@
 package cluster
 
 import (
+	"context"
+	"math"
+	"math/rand"
+
+	"github.com/yinyajun/golearn/matrix"
 	"github.com/yinyajun/golearn/utils"
 
 	"gonum.org/v1/gonum/mat"
 )
 
+const (
+	DenseSolver   = "dense"
+	LanczosSolver = "lanczos"
+)
+
+const (
+	UnnormalizedLaplacian = "unnormalized" // L = D - W
+	SymmetricLaplacian    = "symmetric"    // L_sym = I - D^(-1/2) W D^(-1/2)
+	RandomWalkLaplacian   = "random_walk"  // L_rw = I - D^(-1) W（Shi & Malik, 2000）
+)
+
 type SpecFactorize struct {
-	Norm    bool       // 拉普拉斯矩阵是否归一化
-	Verbose bool       // 冗余模式
-	eVal    []float64  // 特征值(升序)
-	eVec    *mat.Dense // 特征向量
-	done    bool
+	Norm        bool        // 拉普拉斯矩阵是否归一化（Laplacian为空时生效：true为symmetric，false为unnormalized）
+	Laplacian   string      // 拉普拉斯矩阵类型："unnormalized", "symmetric", "random_walk"（非空时覆盖Norm）
+	Verbose     bool        // 冗余模式
+	Solver      string      // 特征分解方法："dense"为完整分解（不接受稀疏矩阵），"lanczos"只求NEigen个特征对
+	NEigen      int         // lanczos求解的特征对个数（NEigen > 0为L最小的NEigen个，NEigen < 0为L最大的-NEigen个）
+	Tol         float64     // lanczos的收敛阈值（残差 <= Tol * ||L||）
+	MaxIter     int         // lanczos的最大迭代次数（即Krylov子空间的最大维度）
+	RandomState rand.Source // lanczos初始向量的随机源
+	eVal        []float64   // 特征值(升序)
+	eVec        *mat.Dense  // 特征向量
+	offset      int         // eVal[0]在完整的升序特征值中的下标（lanczos只求部分特征对）
+	report      *EigenReport
+	done        bool
+}
+
+// EigenReport 特征分解的收敛情况
+type EigenReport struct {
+	Solver     string
+	Iterations int       // lanczos的迭代次数（dense为0）
+	Converged  bool      // 所有特征对的残差是否都满足Tol
+	Residuals  []float64 // 各个特征对的残差||Lx - λx||，与EigenValues()一一对应（dense为nil）
 }
 
 func NewSpecFactorize(verbose, norm bool) *SpecFactorize {
-	return &SpecFactorize{Verbose: verbose, Norm: norm}
+	return &SpecFactorize{
+		Verbose: verbose,
+		Norm:    norm,
+		Solver:  DenseSolver,
+		Tol:     1e-8,
+		MaxIter: 1000,
+	}
 }
 
 func (r *SpecFactorize) Fit(X mat.Symmetric) error {
-	return r.partialFit(X)
+	return r.FitContext(context.Background(), X)
 }
 
-func (r *SpecFactorize) partialFit(adj mat.Symmetric) (err error) {
-	var (
-		dim = adj.Symmetric()
-		es  = mat.EigenSym{}
-		L   mat.Symmetric
-	)
-	r.eVec = mat.NewDense(dim, dim, nil)
-	r.eVal = make([]float64, dim)
+// FitContext 同Fit，在构造拉普拉斯矩阵与特征分解之间（lanczos为每次迭代）检查ctx
+func (r *SpecFactorize) FitContext(ctx context.Context, X mat.Symmetric) error {
+	return r.partialFit(ctx, X)
+}
+
+func (r *SpecFactorize) check(adj mat.Symmetric) error {
+	n := adj.Symmetric()
+	if n == 0 {
+		return ErrEmptyInput
+	}
+	if typ := r.laplacian(); typ != UnnormalizedLaplacian && typ != SymmetricLaplacian && typ != RandomWalkLaplacian {
+		return &ParamError{Field: "Laplacian", Value: r.Laplacian}
+	}
+	switch r.Solver {
+	case DenseSolver:
+		// 稀疏矩阵的完整分解需要先转为稠密矩阵，由调用方显式转换（见factorizeInput）或者改用lanczos
+		if _, ok := adj.(*matrix.SymCSR); ok {
+			return &ParamError{Field: "Solver", Value: r.Solver}
+		}
+		return nil
+	case LanczosSolver:
+	default:
+		return &ParamError{Field: "Solver", Value: r.Solver}
+	}
+	if r.NEigen == 0 || r.NEigen < -n || r.NEigen > n {
+		return &ParamError{Field: "NEigen", Value: r.NEigen}
+	}
+	if !(r.Tol > 0) {
+		return &ParamError{Field: "Tol", Value: r.Tol}
+	}
+	if r.MaxIter < 1 {
+		return &ParamError{Field: "MaxIter", Value: r.MaxIter}
+	}
+	return nil
+}
 
-	if r.Norm {
-		L = NormedLaplacianMatrix(adj)
+func (r *SpecFactorize) partialFit(ctx context.Context, adj mat.Symmetric) (err error) {
+	if err = r.check(adj); err != nil {
+		return err
+	}
+	L := laplacian(adj, r.laplacian())
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	if r.Solver == LanczosSolver {
+		err = r.lanczos(ctx, L)
 	} else {
-		L = LaplacianMatrix(adj)
+		err = r.dense(ctx, L)
+	}
+	if err != nil {
+		return err
+	}
+	if r.laplacian() == RandomWalkLaplacian {
+		r.randomWalkVectors(adj)
+	}
+	r.done = true
+	return
+}
+
+// laplacian 实际使用的拉普拉斯矩阵类型
+func (r *SpecFactorize) laplacian() string {
+	if r.Laplacian != "" {
+		return r.Laplacian
+	}
+	return utils.If(r.Norm, SymmetricLaplacian, UnnormalizedLaplacian).(string)
+}
+
+// factorizeInput 用solver分解sim前的输入：dense求解时稀疏矩阵先转为稠密矩阵，lanczos直接使用稀疏矩阵
+func factorizeInput(sim mat.Symmetric, solver string) mat.Symmetric {
+	if sp, ok := sim.(*matrix.SymCSR); ok && solver == DenseSolver {
+		return sp.ToSymDense()
+	}
+	return sim
+}
+
+// randomWalkVectors L_rw与L_sym的特征值相同，特征向量为 D^(-1/2) u（u为L_sym的特征向量），
+// 变换后每一列重新归一化为单位向量
+func (r *SpecFactorize) randomWalkVectors(adj mat.Symmetric) {
+	scaleRows(r.eVec, invSqrtDegrees(adj))
+	_, c := r.eVec.Dims()
+	for j := 0; j < c; j++ {
+		col := r.eVec.ColView(j).(*mat.VecDense)
+		if norm := mat.Norm(col, 2); norm > 0 {
+			col.ScaleVec(1/norm, col)
+		}
 	}
+}
 
+// dense 完整的特征分解
+func (r *SpecFactorize) dense(ctx context.Context, L mat.Symmetric) error {
+	var (
+		dim = L.Symmetric()
+		es  = mat.EigenSym{}
+	)
 	ok := es.Factorize(L, true)
 	if !ok {
 		return ErrEigenFactorization
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.eVec = mat.NewDense(dim, dim, nil)
+	r.eVal = make([]float64, dim)
 	es.Values(r.eVal)
 	es.VectorsTo(r.eVec)
-	r.done = true
-	return
+	r.offset = 0
+	r.report = &EigenReport{Solver: DenseSolver, Converged: true}
+	return nil
 }
 
 func (r *SpecFactorize) HasFitted() bool { return r.done }
@@ -61,45 +184,68 @@ func (r *SpecFactorize) SmallKEigenVectors(k int) *mat.Dense {
 	// k < 0 : L'最小k个，L的最大k个eigen vector
 	// L'的最大的k个eigen value对应的eigen vector(=> L的最小k个eigen vector)
 	utils.Assert(r.HasFitted(), ErrFitHasNotDone)
-	n := len(r.eVal)
-	// todo: check k
+	n := r.eVec.RawMatrix().Rows
 	lo, hi := n-k, n
 	if k < 0 {
 		lo, hi = 0, -k
 	}
-	return r.eVec.Slice(0, n, lo, hi).(*mat.Dense)
+	utils.Assert(k != 0 && r.available(lo, hi), &ParamError{Field: "k", Value: k})
+	return r.eVec.Slice(0, n, lo-r.offset, hi-r.offset).(*mat.Dense)
 }
 
 // SmallNthEigenVector 获得L=D-A的第N小特征向量（k>0）
 func (r *SpecFactorize) SmallNthEigenVector(k int) *mat.VecDense {
 	utils.Assert(r.HasFitted(), ErrFitHasNotDone)
-	// todo:check k
-	n := len(r.eVal)
+	n := r.eVec.RawMatrix().Rows
+	t := n - k
+	if k < 0 {
+		t = -k - 1
+	}
+	utils.Assert(k != 0 && r.available(t, t+1), &ParamError{Field: "k", Value: k})
+	return r.eVec.Slice(0, n, t-r.offset, t-r.offset+1).(*mat.Dense).ColView(0).(*mat.VecDense)
+}
+
+// SmallNthEigenValue 获得L=D-A的第N小特征值（k>0），k<0时为第-k大特征值
+func (r *SpecFactorize) SmallNthEigenValue(k int) float64 {
+	utils.Assert(r.HasFitted(), ErrFitHasNotDone)
+	n := r.eVec.RawMatrix().Rows
 	t := n - k
 	if k < 0 {
 		t = -k - 1
 	}
-	return r.eVec.Slice(0, n, t, t+1).(*mat.Dense).ColView(0).(*mat.VecDense)
+	utils.Assert(k != 0 && r.available(t, t+1), &ParamError{Field: "k", Value: k})
+	return -r.eVal[t-r.offset] // 分解的是L' = -L
 }
 
+// EigenValues L'的特征值（升序），lanczos只包含求得的部分
 func (r *SpecFactorize) EigenValues() []float64 {
 	utils.Assert(r.HasFitted(), ErrFitHasNotDone)
 	return r.eVal
 }
 
+// EigenVectors 与EigenValues对应的特征向量（按列）
 func (r *SpecFactorize) EigenVectors() *mat.Dense {
 	utils.Assert(r.HasFitted(), ErrFitHasNotDone)
 	return r.eVec
 }
 
+// Report 特征分解的收敛情况
+func (r *SpecFactorize) Report() *EigenReport {
+	utils.Assert(r.HasFitted(), ErrFitHasNotDone)
+	return r.report
+}
+
+// available 完整升序特征值中下标[lo, hi)的特征对是否已经求得
+func (r *SpecFactorize) available(lo, hi int) bool {
+	return r.offset <= lo && lo < hi && hi <= r.offset+len(r.eVal)
+}
+
 // DegreeMatrix 从无向图的邻接矩阵计算度矩阵
 func DegreeMatrix(m mat.Symmetric) *mat.DiagDense {
 	n := m.Symmetric()
 	d := make([]float64, n)
 	for i := 0; i < n; i++ {
-		for j := 0; j < n; j++ {
-			d[i] += m.At(i, j)
-		}
+		matrix.DoRowNonZero(m, i, func(j int, v float64) { d[i] += v })
 	}
 	return mat.NewDiagDense(n, d)
 }
@@ -123,20 +269,92 @@ func LaplacianMatrix(m mat.Symmetric) *mat.SymDense {
 	return L
 }
 
-// NormedLaplacianMatrix 计算 L' = D^(-1/2) L' D^(-1/2)
+// NormedLaplacianMatrix 计算 L' = D^(-1/2) L' D^(-1/2) = D^(-1/2) W D^(-1/2) - I
+// 度为0的孤立点，其对应的行列为0（对角元素仍为-1）
 func NormedLaplacianMatrix(m mat.Symmetric) *mat.SymDense {
 	n := m.Symmetric()
 	L := mat.NewSymDense(n, nil)
-	D := DegreeMatrix(m)
+	s := invSqrtDegrees(m)
 
 	for i := 0; i < n; i++ {
 		for j := i; j < n; j++ {
 			if i == j {
-				L.SetSym(i, i, -1)
+				L.SetSym(i, i, m.At(i, i)*s[i]*s[i]-1)
 				continue
 			}
-			L.SetSym(i, j, m.At(i, j)/(D.At(i, i)*D.At(j, j)))
+			L.SetSym(i, j, m.At(i, j)*s[i]*s[j])
 		}
 	}
 	return L
 }
+
+// SparseLaplacianMatrix 稀疏版本的LaplacianMatrix，L' = W - D
+func SparseLaplacianMatrix(m *matrix.SymCSR) *matrix.SymCSR {
+	var (
+		n                = m.Symmetric()
+		D                = DegreeMatrix(m)
+		rows, cols, vals = upperTriplets(m, func(i, j int, v float64) float64 { return v })
+	)
+	for i := 0; i < n; i++ {
+		rows, cols = append(rows, i), append(cols, i)
+		vals = append(vals, -D.At(i, i))
+	}
+	return matrix.NewSymCSR(n, rows, cols, vals)
+}
+
+// SparseNormedLaplacianMatrix 稀疏版本的NormedLaplacianMatrix，L' = D^(-1/2) W D^(-1/2) - I
+func SparseNormedLaplacianMatrix(m *matrix.SymCSR) *matrix.SymCSR {
+	var (
+		n                = m.Symmetric()
+		s                = invSqrtDegrees(m)
+		rows, cols, vals = upperTriplets(m, func(i, j int, v float64) float64 { return v * s[i] * s[j] })
+	)
+	for i := 0; i < n; i++ {
+		rows, cols = append(rows, i), append(cols, i)
+		vals = append(vals, -1)
+	}
+	return matrix.NewSymCSR(n, rows, cols, vals)
+}
+
+// laplacian 根据邻接矩阵的类型（稠密或稀疏）计算L'
+// random_walk与symmetric分解的是同一个矩阵，特征向量由randomWalkVectors变换
+func laplacian(adj mat.Symmetric, typ string) mat.Symmetric {
+	norm := typ != UnnormalizedLaplacian
+	if sp, ok := adj.(*matrix.SymCSR); ok {
+		if norm {
+			return SparseNormedLaplacianMatrix(sp)
+		}
+		return SparseLaplacianMatrix(sp)
+	}
+	if norm {
+		return NormedLaplacianMatrix(adj)
+	}
+	return LaplacianMatrix(adj)
+}
+
+// invSqrtDegrees 计算D^(-1/2)的对角元素，度为0时取0
+func invSqrtDegrees(m mat.Symmetric) []float64 {
+	var (
+		D = DegreeMatrix(m)
+		s = make([]float64, m.Symmetric())
+	)
+	for i := range s {
+		if d := D.At(i, i); d > 0 {
+			s[i] = 1 / math.Sqrt(d)
+		}
+	}
+	return s
+}
+
+// upperTriplets 以三元组形式取出稀疏矩阵上三角（含对角）的非零元素，元素值经过fn变换
+func upperTriplets(m *matrix.SymCSR, fn func(i, j int, v float64) float64) (rows, cols []int, vals []float64) {
+	for i := 0; i < m.Symmetric(); i++ {
+		m.DoRowNonZero(i, func(i, j int, v float64) {
+			if j >= i {
+				rows, cols = append(rows, i), append(cols, j)
+				vals = append(vals, fn(i, j, v))
+			}
+		})
+	}
+	return
+}
@@ -0,0 +1,250 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/19 16:05
+ */
+
+package cluster
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/yinyajun/golearn/matrix"
+	"github.com/yinyajun/golearn/utils"
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestNormedLaplacianMatrix(t *testing.T) {
+	// 度不相等的带权图，度为 3, 5, 4, 2
+	W := mat.NewSymDense(4, []float64{
+		0, 2, 1, 0,
+		2, 0, 2, 1,
+		1, 2, 0, 1,
+		0, 1, 1, 0,
+	})
+	d := []float64{3, 5, 4, 2}
+	L := NormedLaplacianMatrix(W)
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			expected := W.At(i, j) / math.Sqrt(d[i]*d[j])
+			if i == j {
+				expected -= 1
+			}
+			if math.Abs(L.At(i, j)-expected) > 1e-12 {
+				t.Fatalf("L(%d,%d) = %f, expected %f", i, j, L.At(i, j), expected)
+			}
+		}
+	}
+
+	// D^(1/2)·1 是特征值为0的特征向量
+	u := mat.NewVecDense(4, nil)
+	for i := range d {
+		u.SetVec(i, math.Sqrt(d[i]))
+	}
+	u.MulVec(L, u)
+	if mat.Norm(u, 2) > 1e-12 {
+		t.Errorf("D^(1/2)·1 should be in the null space, got %v", u.RawVector().Data)
+	}
+}
+
+// ringGraph 环上的每个点与前后各hop个点相连，另加随机的弱边，保证图是连通的
+func ringGraph(n, hop int, seed int64) *matrix.SymCSR {
+	var (
+		rng        = rand.New(rand.NewSource(seed))
+		rows, cols []int
+		vals       []float64
+	)
+	for i := 0; i < n; i++ {
+		for h := 1; h <= hop; h++ {
+			rows, cols = append(rows, i), append(cols, (i+h)%n)
+			vals = append(vals, 1+rng.Float64())
+		}
+		rows, cols = append(rows, i), append(cols, rng.Intn(n))
+		vals = append(vals, 0.1*rng.Float64())
+	}
+	return matrix.NewSymCSR(n, rows, cols, vals)
+}
+
+func TestSpecFactorize_Lanczos(t *testing.T) {
+	sim := ringGraph(120, 3, 5)
+
+	for _, k := range []int{4, -3} {
+		for _, norm := range []string{UnnormalizedLaplacian, SymmetricLaplacian, RandomWalkLaplacian} {
+			dense := NewSpecFactorize(false, false)
+			dense.Laplacian = norm
+			if err := dense.Fit(sim.ToSymDense()); err != nil {
+				t.Fatal(err)
+			}
+
+			fac := NewSpecFactorize(false, false)
+			fac.Laplacian, fac.Solver, fac.NEigen, fac.Tol = norm, LanczosSolver, k, 1e-10
+			fac.RandomState = rand.NewSource(1)
+			if err := fac.Fit(sim); err != nil {
+				t.Fatal(err)
+			}
+			if report := fac.Report(); !report.Converged {
+				t.Fatalf("k=%d norm=%v: not converged %+v", k, norm, report)
+			}
+
+			var (
+				n      = sim.Symmetric()
+				want   = dense.EigenValues()
+				got    = fac.EigenValues()
+				offset = 0
+			)
+			if k > 0 {
+				offset = n - k
+			}
+			for i, v := range got {
+				if math.Abs(v-want[offset+i]) > 1e-8 {
+					t.Fatalf("k=%d norm=%v: eigenvalue %d expected %v, got %v", k, norm, i, want[offset+i], v)
+				}
+			}
+			// 特征向量只相差符号（测试图的特征值没有重根）
+			a, b := dense.SmallNthEigenVector(k), fac.SmallNthEigenVector(k)
+			if math.Abs(math.Abs(mat.Dot(a, b))-1) > 1e-6 {
+				t.Fatalf("k=%d norm=%v: eigenvector mismatch, dot=%v", k, norm, mat.Dot(a, b))
+			}
+		}
+	}
+}
+
+func TestSpecFactorize_LanczosRepeated(t *testing.T) {
+	// 3个互不相连的团：L的特征值为0（3重）与10（27重）
+	sim, _ := cliques(3, 10, 0)
+	L := laplacian(sim, UnnormalizedLaplacian)
+
+	for _, k := range []int{4, -5} {
+		dense := NewSpecFactorize(false, false)
+		if err := dense.Fit(sim.ToSymDense()); err != nil {
+			t.Fatal(err)
+		}
+		fac := NewSpecFactorize(false, false)
+		fac.Solver, fac.NEigen = LanczosSolver, k
+		fac.RandomState = rand.NewSource(2)
+		if err := fac.Fit(sim); err != nil {
+			t.Fatal(err)
+		}
+
+		var (
+			n      = sim.Symmetric()
+			want   = dense.EigenValues()
+			got    = fac.EigenValues()
+			V      = fac.EigenVectors()
+			offset = 0
+		)
+		if k > 0 {
+			offset = n - k
+		}
+		for i, v := range got {
+			if math.Abs(v-want[offset+i]) > 1e-6 {
+				t.Fatalf("k=%d: eigenvalues expected %v, got %v", k, want[offset:offset+len(got)], got)
+			}
+		}
+		// 重特征值的各个副本是正交的特征向量
+		var gram, LV mat.Dense
+		gram.Mul(V.T(), V)
+		for i := range got {
+			for j := range got {
+				if math.Abs(gram.At(i, j)-utils.If(i == j, 1.0, 0.0).(float64)) > 1e-8 {
+					t.Fatalf("k=%d: eigenvectors are not orthonormal", k)
+				}
+			}
+		}
+		LV.Mul(L, V)
+		for j, v := range got {
+			col := mat.NewVecDense(n, nil)
+			col.AddScaledVec(LV.ColView(j), -v, V.ColView(j))
+			if mat.Norm(col, 2) > 1e-6 {
+				t.Fatalf("k=%d: residual of eigenpair %d is %e", k, j, mat.Norm(col, 2))
+			}
+		}
+	}
+}
+
+func TestSpecFactorize_LanczosNotConverged(t *testing.T) {
+	fac := NewSpecFactorize(false, false)
+	fac.Solver, fac.NEigen, fac.MaxIter, fac.Tol = LanczosSolver, 4, 6, 1e-12
+	fac.RandomState = rand.NewSource(1)
+	if err := fac.Fit(ringGraph(120, 3, 5)); err != ErrNotConverged {
+		t.Errorf("expected ErrNotConverged, got %v", err)
+	}
+}
+
+func TestSpecFactorize_LanczosParamError(t *testing.T) {
+	fac := NewSpecFactorize(false, false)
+	fac.Solver = LanczosSolver
+	if err := fac.Fit(ringGraph(10, 1, 1)); err == nil {
+		t.Errorf("expected error for NEigen=0")
+	}
+	fac.Solver = "qr"
+	if err := fac.Fit(ringGraph(10, 1, 1)); err == nil {
+		t.Errorf("expected error for unknown solver")
+	}
+	fac.Solver = DenseSolver
+	if err := fac.Fit(ringGraph(10, 1, 1)); err == nil {
+		t.Errorf("expected error for sparse input with dense solver")
+	}
+}
+
+func TestSpecFactorize_Norm(t *testing.T) {
+	sim := ringGraph(30, 2, 4).ToSymDense()
+
+	// Laplacian为空时由Norm决定
+	for norm, laplacian := range map[bool]string{false: UnnormalizedLaplacian, true: SymmetricLaplacian} {
+		a, b := NewSpecFactorize(false, norm), NewSpecFactorize(false, !norm)
+		b.Laplacian = laplacian
+		if err := a.Fit(sim); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Fit(sim); err != nil {
+			t.Fatal(err)
+		}
+		if !floats.EqualApprox(a.EigenValues(), b.EigenValues(), 1e-10) {
+			t.Errorf("norm=%v should be the same as laplacian=%q", norm, laplacian)
+		}
+	}
+}
+
+func TestSpecFactorize_RandomWalk(t *testing.T) {
+	var (
+		sim = ringGraph(40, 2, 3).ToSymDense()
+		n   = sim.Symmetric()
+		fac = NewSpecFactorize(false, false)
+	)
+	fac.Laplacian = RandomWalkLaplacian
+	if err := fac.Fit(sim); err != nil {
+		t.Fatal(err)
+	}
+	deg := make([]float64, n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			deg[i] += sim.At(i, j)
+		}
+	}
+	// L_rw v = lambda v 等价于 (D - W) v = lambda D v
+	for _, k := range []int{1, 2, 5, -1} {
+		var (
+			v      = fac.SmallNthEigenVector(k)
+			lambda = fac.SmallNthEigenValue(k)
+			wv     mat.VecDense
+		)
+		if math.Abs(mat.Norm(v, 2)-1) > 1e-10 {
+			t.Fatalf("k=%d: eigenvector not normalized", k)
+		}
+		wv.MulVec(sim, v)
+		for i := 0; i < n; i++ {
+			lhs := deg[i]*v.AtVec(i) - wv.AtVec(i)
+			if math.Abs(lhs-lambda*deg[i]*v.AtVec(i)) > 1e-8 {
+				t.Fatalf("k=%d: row %d expected %v, got %v", k, i, lambda*deg[i]*v.AtVec(i), lhs)
+			}
+		}
+	}
+	fac = NewSpecFactorize(false, true)
+	fac.Laplacian = "rw"
+	if err := fac.Fit(sim); err == nil {
+		t.Errorf("expected error for unknown laplacian")
+	}
+}
@@ -6,11 +6,11 @@
 package cluster
 
 import (
+	"context"
 	"log"
 	"math"
 	"math/rand"
 	"runtime"
-	"time"
 
 	"github.com/yinyajun/golearn/matrix"
 	"github.com/yinyajun/golearn/utils"
@@ -18,17 +18,24 @@ import (
 )
 
 const (
-	Full  = "full"
-	Elkan = "elkan"
+	Full        = "full"
+	Elkan       = "elkan"
+	Constrained = "constrained"
 )
 
+// checkInterval 并发计算时每处理checkInterval个点检查一次ctx是否已取消
+const checkInterval = 256
+
 type KMeans struct {
-	NClusters   int    // 聚类数
-	MaxIter     int    // 最大迭代次数
-	NInit       int    // 聚类次数（因为kmeans可能陷入local minima， 多次聚类取最好的一次）
-	Verbose     bool   // 冗余模式
-	NGoroutines int    // 计算并发程度
-	Algorithm   string // 采用算法 "full"原始EM方式；"elkan"
+	NClusters   int         // 聚类数
+	MaxIter     int         // 最大迭代次数
+	NInit       int         // 聚类次数（因为kmeans可能陷入local minima， 多次聚类取最好的一次）
+	Verbose     bool        // 冗余模式
+	NGoroutines int         // 计算并发程度
+	Algorithm   string      // 采用算法 "full"原始EM方式；"elkan"；"constrained"类大小受限（分配为最小费用流问题）
+	MinSize     int         // constrained时每个类的最小样本数（0时按1处理）
+	MaxSize     int         // constrained时每个类的最大样本数（0时不限制）
+	RandomState rand.Source // 随机源，每次初始化的种子由它派生（nil时以当前时间为种子；不要在并发的Fit间共享）
 	centers     *mat.Dense
 	labels      []int
 	cost        float64
@@ -42,53 +49,110 @@ func NewKMeans(NClusters int) *KMeans {
 		MaxIter:     50,
 		NInit:       3,
 		Verbose:     false,
-		NGoroutines: runtime.NumCPU() / 2,
+		NGoroutines: utils.If(runtime.NumCPU() > 1, runtime.NumCPU()/2, 1).(int),
 		Algorithm:   Full,
 	}
 	return m
 }
 
-func (m *KMeans) checkParams(points *mat.Dense) {
+func (m *KMeans) checkParams(points *mat.Dense) error {
 	if points.IsEmpty() {
-		panic(ErrEmptyInput)
+		return ErrEmptyInput
 	}
 	nSamples, _ := points.Dims()
 	if m.NClusters <= 1 || m.NClusters >= nSamples {
-		panic(ErrInvalidArgument)
+		return &ParamError{Field: "NClusters", Value: m.NClusters}
+	}
+	if m.NGoroutines < 1 || m.NGoroutines >= 100 {
+		return &ParamError{Field: "NGoroutines", Value: m.NGoroutines}
+	}
+	if m.NInit < 1 {
+		return &ParamError{Field: "NInit", Value: m.NInit}
 	}
-	if m.NGoroutines <= 1 || m.NGoroutines >= 100 {
-		panic(ErrInvalidArgument)
+	if m.MaxIter < 1 {
+		return &ParamError{Field: "MaxIter", Value: m.MaxIter}
 	}
-	if m.NInit < 1 || m.MaxIter < 1 {
-		panic(ErrInvalidArgument)
+	switch m.Algorithm {
+	case Full, Elkan:
+		if m.MinSize != 0 || m.MaxSize != 0 { // 类大小约束只有constrained支持
+			return &ParamError{Field: "Algorithm", Value: m.Algorithm}
+		}
+	case Constrained:
+		lo, hi := m.sizeBounds(nSamples)
+		if m.MinSize < 0 || lo*m.NClusters > nSamples {
+			return &ParamError{Field: "MinSize", Value: m.MinSize}
+		}
+		if m.MaxSize < 0 || hi < lo || hi*m.NClusters < nSamples {
+			return &ParamError{Field: "MaxSize", Value: m.MaxSize}
+		}
+	default:
+		return &ParamError{Field: "Algorithm", Value: m.Algorithm}
 	}
+	return nil
 }
 
 // Fit
 // note that X.floatVal will be modified (subtract mean) during fit time
-func (m *KMeans) Fit(X *mat.Dense) error {
-	m.checkParams(X)
-	return m.partialFit(X)
+func (m *KMeans) Fit(X mat.Matrix) error {
+	return m.FitContext(context.Background(), X)
+}
+
+// FitContext 同Fit，每轮迭代之间检查ctx，取消时返回ctx.Err()（X会被恢复）
+func (m *KMeans) FitContext(ctx context.Context, X mat.Matrix) error {
+	points := asDense(X)
+	if err := m.checkParams(points); err != nil {
+		return err
+	}
+	return m.partialFit(ctx, points, m.RandomState)
+}
+
+func (m *KMeans) FitPredict(X mat.Matrix) ([]int, error) {
+	if err := m.Fit(X); err != nil {
+		return nil, err
+	}
+	return m.Labels(), nil
+}
+
+// Predict 将X的每一行分配到最近的聚类中心（不考虑类大小约束）
+func (m *KMeans) Predict(X mat.Matrix) []int {
+	utils.Assert(m.HasFitted(), ErrFitHasNotDone)
+	labels, _ := m.nearestAll(asDense(X))
+	return labels
 }
 
-func (m *KMeans) partialFit(points *mat.Dense) error {
+func (m *KMeans) NumClusters() int { return m.NClusters }
+
+// partialFit 在points上聚类，每次初始化的种子由src派生（src不必是m.RandomState，调用方可以传入自己的随机源）
+func (m *KMeans) partialFit(ctx context.Context, points *mat.Dense, src rand.Source) (err error) {
 	var (
 		seed        int64
+		rng         = utils.NewRand(src)
 		bestCenters *mat.Dense
 		bestCost    = math.Inf(1)
 		bestLabels  = make([]int, m.nSamples(points))
 	)
 	// subtract of mean of points for more accurate distance computations
-	XMean := matrix.DenseMean(points, 0)
-	matrix.DenseSubVector(points, XMean, 0)
+	XMean, err := matrix.DenseMean(points, 0)
+	if err != nil {
+		return err
+	}
+	if _, err = matrix.DenseSubVector(points, XMean, 0); err != nil {
+		return err
+	}
+	defer matrix.DenseAddVector(points, XMean, 0)
 
 	for i := 0; i < m.NInit; i++ {
-		seed = time.Now().Unix()
+		seed = rng.Int63()
 		switch m.Algorithm {
 		case Full:
-			m.singleFull(points, seed)
+			err = m.singleFull(ctx, points, seed)
 		case Elkan:
-			m.singleElkan(points, seed)
+			err = m.singleElkan(ctx, points, seed)
+		case Constrained:
+			err = m.singleConstrained(ctx, points, seed)
+		}
+		if err != nil {
+			return err
 		}
 		if m.cost < bestCost {
 			copy(bestLabels, m.labels)
@@ -96,8 +160,9 @@ func (m *KMeans) partialFit(points *mat.Dense) error {
 			bestCenters = m.centers
 		}
 	}
-	matrix.DenseAddVector(points, XMean, 0)
-	matrix.DenseAddVector(bestCenters, XMean, 0)
+	if _, err = matrix.DenseAddVector(bestCenters, XMean, 0); err != nil {
+		return err
+	}
 
 	m.labels = bestLabels
 	m.centers = bestCenters
@@ -109,75 +174,45 @@ func (m *KMeans) partialFit(points *mat.Dense) error {
 func (m *KMeans) HasFitted() bool { return m.done }
 
 func (m *KMeans) Transform(X mat.Vector) int {
-	var (
-		dist             float64
-		cluster, minDist = 0, math.Inf(1)
-	)
-
-	for k := 0; k < m.NClusters; k++ {
-		dist = utils.EuclideanSquare(X, m.centers.RowView(k))
-		if dist < minDist {
-			cluster, minDist = k, dist
-		}
-	}
+	cluster, _ := m.nearest(X)
 	return cluster
 }
 
-func (m *KMeans) singleFull(points *mat.Dense, seed int64) {
+func (m *KMeans) singleFull(ctx context.Context, points *mat.Dense, seed int64) error {
 	m.initCenters(points, seed)
 	for iter := 0; iter < m.MaxIter && !m.unchanged; iter++ {
-		m.assign(points)
+		if err := m.assign(ctx, points); err != nil {
+			return err
+		}
 		m.update(points)
 		if m.Verbose {
 			log.Printf("[Epoch %d] Cost: %f\n", iter, m.cost)
 		}
 	}
+	return nil
 }
 
 // assign 将所有点分配到最近的聚类中心（类似于EM中的E步）
-func (m *KMeans) assign(points *mat.Dense) {
-	var (
-		limit    = make(chan struct{}, m.NGoroutines)
-		done     = make(chan struct{})
-		converge = make(chan bool)
-	)
-
-	_assign := func(i int) bool {
-		var dist float64
-		cluster, minDist := 0, math.Inf(1)
-		for k := 0; k < m.NClusters; k++ {
-			dist = utils.EuclideanSquare(points.RowView(i), m.centers.RowView(k))
-			if dist < minDist {
-				cluster, minDist = k, dist
+func (m *KMeans) assign(ctx context.Context, points *mat.Dense) error {
+	changed := m.parallel(m.nSamples(points), func(lo, hi int) (changed int) {
+		var cluster int
+		for i := lo; i < hi; i++ {
+			if i%checkInterval == 0 && ctx.Err() != nil {
+				return
+			}
+			cluster, _ = m.nearest(points.RowView(i))
+			if m.labels[i] != cluster {
+				m.labels[i] = cluster
+				changed++
 			}
 		}
-		if m.labels[i] == cluster {
-			return true
-		}
-		m.labels[i] = cluster
-		return false
-	}
-
-	go func() {
-		m.unchanged = true
-		for i := 0; i < m.nSamples(points); i++ {
-			m.unchanged = <-converge && m.unchanged // note short circuit
-		}
-		done <- struct{}{}
-	}()
-
-	for i := 0; i < m.nSamples(points); i++ {
-		limit <- struct{}{}
-		go func(i int) {
-			converge <- _assign(i)
-			<-limit
-		}(i)
+		return
+	})
+	if err := ctx.Err(); err != nil {
+		return err
 	}
-	<-done
-
-	close(limit)
-	close(converge)
-	close(done)
+	m.unchanged = changed == 0
+	return nil
 }
 
 // update 更新聚类中心（类似于EM中的M步）
@@ -201,6 +236,9 @@ func (m *KMeans) update(points *mat.Dense) {
 			centroid.AddVec(centroid, points.RowView(x))
 			cnt++
 		}
+		if cnt == 0 { // 空簇保持原中心（与elkan一致），没有点也就没有cost
+			return 0
+		}
 		centroid.ScaleVec(1/float64(cnt), centroid)
 
 		m.centers.SetRow(k, centroid.RawVector().Data)
@@ -227,15 +265,15 @@ func (m *KMeans) update(points *mat.Dense) {
 	close(costs)
 }
 
-func (m *KMeans) singleElkan(points *mat.Dense, seed int64) {
-	m.initCenters(points, seed)
-	// todo: to be implemented
-	panic("not implemented")
+func (m *KMeans) Center(i int) mat.Vector {
+	utils.Assert(m.HasFitted(), ErrFitHasNotDone)
+	return m.centers.RowView(i)
 }
 
-func (m *KMeans) Center(i int) mat.Vector { return m.centers.RowView(i) }
-
-func (m *KMeans) Labels() []int { return m.labels }
+func (m *KMeans) Labels() []int {
+	utils.Assert(m.HasFitted(), ErrFitHasNotDone)
+	return m.labels
+}
 
 func (m *KMeans) Cost() float64 { return m.cost }
 
@@ -249,18 +287,18 @@ func (m *KMeans) initCenters(points *mat.Dense, seed int64) {
 	_, nFeatures := points.Dims()
 	m.centers = mat.NewDense(m.NClusters, nFeatures, nil)
 	m.labels = make([]int, m.nSamples(points))
+	m.unchanged = false
 
 	var (
 		minDist float64
 		centers = make([]int, m.NClusters)
-		sampler = utils.NewSampler(m.nSamples(points))
+		rng     = rand.New(rand.NewSource(seed))
+		sampler = utils.NewRandSampler(m.nSamples(points), rng)
 		chosen  = make(map[int]struct{})
 	)
 
-	rand.Seed(seed)
-
 	// 初始化第一个点
-	centers[0] = rand.Intn(m.nSamples(points))
+	centers[0] = rng.Intn(m.nSamples(points))
 	chosen[centers[0]] = struct{}{}
 
 	// 初始化其余点（计算每个数据点和已有簇中心之间的最短距离，生成该样本被选为聚类中心的概率）
@@ -6,9 +6,14 @@
 package cluster
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
+	"math/rand"
 	"testing"
 
+	"github.com/yinyajun/golearn/cluster/metrics"
 	"gonum.org/v1/gonum/mat"
 )
 
@@ -42,17 +47,266 @@ func TestKMeans_Fit(t *testing.T) {
 		panic(err.Error())
 	}
 
-	clusters := []int{0, 1, 4, 9, 10, 12, 15, 16, 17, 19}
-	var n int
-
-	for _, i := range clusters {
-		n += k.Labels()[i]
+	truth := make([]int, 20)
+	for _, i := range []int{0, 1, 4, 9, 10, 12, 15, 16, 17, 19} {
+		truth[i] = 1
 	}
 	fmt.Println(k.Center(0))
 	fmt.Println(k.Center(1))
-	if n == 0 || n == 10 {
-		return
+	if score, _ := metrics.AdjustedRandIndex(truth, k.Labels()); score != 1 {
+		t.Errorf("unexpected cluster result, ari=%f", score)
+	}
+}
+
+func blobs(n, nFeatures, nCenters int, seed int64) *mat.Dense {
+	r := rand.New(rand.NewSource(seed))
+	centers := mat.NewDense(nCenters, nFeatures, nil)
+	for k := 0; k < nCenters; k++ {
+		for j := 0; j < nFeatures; j++ {
+			centers.Set(k, j, r.Float64()*20-10)
+		}
+	}
+	data := mat.NewDense(n, nFeatures, nil)
+	for i := 0; i < n; i++ {
+		k := r.Intn(nCenters)
+		for j := 0; j < nFeatures; j++ {
+			data.Set(i, j, centers.At(k, j)+r.NormFloat64())
+		}
 	}
-	t.Errorf("unexpected cluster result")
+	return data
+}
+
+func TestKMeans_Elkan(t *testing.T) {
+	data := blobs(600, 5, 6, 1)
+
+	full := NewKMeans(6)
+	_ = full.singleFull(context.Background(), data, 7)
 
+	elkan := NewKMeans(6)
+	elkan.Algorithm = Elkan
+	_ = elkan.singleElkan(context.Background(), data, 7)
+
+	for i := range full.labels {
+		if full.labels[i] != elkan.labels[i] {
+			t.Fatalf("label of %d differs: full=%d, elkan=%d", i, full.labels[i], elkan.labels[i])
+		}
+	}
+	if math.Abs(full.Cost()-elkan.Cost()) > 1e-6*full.Cost() {
+		t.Errorf("cost differs: full=%f, elkan=%f", full.Cost(), elkan.Cost())
+	}
+
+	elkan = NewKMeans(6)
+	elkan.Algorithm = Elkan
+	if err := elkan.Fit(data); err != nil {
+		t.Fatal(err)
+	}
+	if !elkan.HasFitted() {
+		t.Errorf("elkan should be fitted")
+	}
+}
+
+func TestKMeans_EmptyCluster(t *testing.T) {
+	data := mat.NewDense(4, 2, []float64{0, 0, 1, 0, 10, 0, 11, 0})
+
+	// 第2个中心离所有点都远，分配后为空簇，更新时应保持原中心（与elkan一致）
+	m := NewKMeans(3)
+	m.labels = make([]int, 4)
+	m.centers = mat.NewDense(3, 2, []float64{0.5, 0, 10.5, 0, 100, 0})
+	if err := m.assign(context.Background(), data); err != nil {
+		t.Fatal(err)
+	}
+	m.update(data)
+	if !mat.Equal(m.centers.RowView(2), mat.NewVecDense(2, []float64{100, 0})) {
+		t.Errorf("empty cluster should keep its center, got %v", m.Center(2))
+	}
+	if m.Cost() != 1 {
+		t.Errorf("expected cost 1, got %f", m.Cost())
+	}
+}
+
+func TestKMeans_Constrained(t *testing.T) {
+	data := blobs(300, 2, 3, 3)
+
+	// 不限制类大小时与full的迭代过程一致
+	full := NewKMeans(4)
+	_ = full.singleFull(context.Background(), data, 7)
+	loose := NewKMeans(4)
+	loose.Algorithm = Constrained
+	_ = loose.singleConstrained(context.Background(), data, 7)
+	for i := range full.labels {
+		if full.labels[i] != loose.labels[i] {
+			t.Fatalf("label of %d differs: full=%d, constrained=%d", i, full.labels[i], loose.labels[i])
+		}
+	}
+
+	c := NewKMeans(4)
+	c.Algorithm, c.MinSize, c.MaxSize = Constrained, 70, 80
+	c.RandomState = rand.NewSource(1)
+	labels, err := c.FitPredict(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sizes := make([]int, 4)
+	for _, label := range labels {
+		sizes[label]++
+	}
+	for k, size := range sizes {
+		if size < 70 || size > 80 {
+			t.Errorf("size of cluster %d is %d, expected in [70, 80]", k, size)
+		}
+	}
+
+	for _, tc := range []struct {
+		algorithm        string
+		minSize, maxSize int
+		field            string
+	}{
+		{Constrained, 80, 0, "MinSize"},
+		{Constrained, 0, 70, "MaxSize"},
+		{Constrained, 50, 40, "MaxSize"},
+		{Full, 10, 0, "Algorithm"},
+	} {
+		c := NewKMeans(4)
+		c.Algorithm, c.MinSize, c.MaxSize = tc.algorithm, tc.minSize, tc.maxSize
+		var pe *ParamError
+		if err := c.Fit(data); !errors.As(err, &pe) || pe.Field != tc.field {
+			t.Errorf("%+v: expected ParamError on %s, got %v", tc, tc.field, err)
+		}
+	}
+}
+
+func TestMiniBatchKMeans_Fit(t *testing.T) {
+	data := blobs(3000, 4, 3, 2)
+
+	m := NewMiniBatchKMeans(3, 100)
+	m.RandomState = rand.NewSource(1)
+	if err := m.Fit(data); err != nil {
+		t.Fatal(err)
+	}
+	full := NewKMeans(3)
+	full.RandomState = rand.NewSource(1)
+	if err := full.Fit(data); err != nil {
+		t.Fatal(err)
+	}
+	if m.Cost() > 1.1*full.Cost() {
+		t.Errorf("mini-batch cost %f is too far from full cost %f", m.Cost(), full.Cost())
+	}
+}
+
+func TestMiniBatchKMeans_PartialFit(t *testing.T) {
+	data := blobs(3000, 4, 3, 2)
+
+	m := NewMiniBatchKMeans(3, 100)
+	m.RandomState = rand.NewSource(1)
+	for lo := 0; lo < 3000; lo += 300 {
+		if err := m.PartialFit(data.Slice(lo, lo+300, 0, 4).(*mat.Dense)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(m.Labels()) != 300 {
+		t.Errorf("labels should belong to the last batch, got %d", len(m.Labels()))
+	}
+
+	full := NewKMeans(3)
+	full.RandomState = rand.NewSource(1)
+	truth, err := full.FitPredict(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score, _ := metrics.AdjustedRandIndex(truth, m.Predict(data)); score < 0.99 {
+		t.Errorf("centers after partial fit disagree with full kmeans, ari=%f", score)
+	}
+
+	m = NewMiniBatchKMeans(3, 100)
+	m.NGoroutines = 0
+	if err := m.PartialFit(data.Slice(0, 300, 0, 4).(*mat.Dense)); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument for NGoroutines=0, got %v", err)
+	}
+}
+
+func TestKMeans_RandomState(t *testing.T) {
+	data := blobs(200, 3, 4, 3)
+
+	fit := func() *KMeans {
+		k := NewKMeans(4)
+		k.RandomState = rand.NewSource(42)
+		if err := k.Fit(data); err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	a, b := fit(), fit()
+	for i := range a.Labels() {
+		if a.Labels()[i] != b.Labels()[i] {
+			t.Fatalf("labels differ at %d with the same random state", i)
+		}
+	}
+	if a.Cost() != b.Cost() {
+		t.Errorf("cost differs with the same random state: %f, %f", a.Cost(), b.Cost())
+	}
+
+	expected := []int{0, 3, 0, 0, 1, 3, 0, 0, 0, 0, 0, 3, 1, 2, 2, 2, 1, 3, 1, 0}
+	for i, label := range expected {
+		if a.Labels()[i] != label {
+			t.Fatalf("unexpected labels %v", a.Labels()[:len(expected)])
+		}
+	}
+}
+
+func TestKMeans_FitContext(t *testing.T) {
+	data := blobs(500, 3, 4, 4)
+	origin := mat.DenseCopyOf(data)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, algorithm := range []string{Full, Elkan} {
+		k := NewKMeans(4)
+		k.Algorithm = algorithm
+		if err := k.FitContext(ctx, data); err != context.Canceled {
+			t.Errorf("%s: expected context.Canceled, got %v", algorithm, err)
+		}
+		if k.HasFitted() {
+			t.Errorf("%s: canceled fit should not be marked as fitted", algorithm)
+		}
+		if !mat.EqualApprox(data, origin, 1e-9) {
+			t.Errorf("%s: input should be restored after cancellation", algorithm)
+		}
+	}
+}
+
+func TestKMeans_ParamError(t *testing.T) {
+	data := blobs(10, 2, 2, 5)
+
+	k := NewKMeans(10)
+	err := k.Fit(data)
+	var pe *ParamError
+	if !errors.Is(err, ErrInvalidArgument) || !errors.As(err, &pe) || pe.Field != "NClusters" {
+		t.Errorf("expected ParamError on NClusters, got %v", err)
+	}
+
+	defer func() {
+		if r := recover(); r != ErrFitHasNotDone {
+			t.Errorf("expected panic with ErrFitHasNotDone, got %v", r)
+		}
+	}()
+	k.Labels()
+}
+
+func TestKMeans_Predict(t *testing.T) {
+	data := blobs(300, 3, 3, 6)
+
+	var c Clusterer = NewKMeans(3)
+	labels, err := c.FitPredict(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	predicted := c.Predict(data)
+	for i := range labels {
+		if labels[i] != predicted[i] {
+			t.Fatalf("predict of %d differs from fit result: %d, %d", i, predicted[i], labels[i])
+		}
+	}
+	if c.NumClusters() != 3 || !c.HasFitted() {
+		t.Errorf("unexpected state of clusterer")
+	}
 }
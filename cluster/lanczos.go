@@ -0,0 +1,227 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/19 14:37
+ */
+
+package cluster
+
+import (
+	"context"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/yinyajun/golearn/matrix"
+	"github.com/yinyajun/golearn/utils"
+	"gonum.org/v1/gonum/mat"
+)
+
+// lanczosCheckStep 两次收敛检查之间Krylov子空间至少扩张的维数
+// 检查需要对T_m做特征分解，因此检查间隔随m增长（每次至少扩张m/4维）
+const lanczosCheckStep = 8
+
+// ritzPair Lanczos求得的（已收敛的）Ritz对
+type ritzPair struct {
+	value    float64
+	vector   *mat.VecDense
+	residual float64
+}
+
+// lanczos 带完全重正交化的Lanczos迭代，只求L'两端的|NEigen|个特征对
+// 单向量Lanczos在Krylov子空间中对每个重特征值只能得到一个副本（例如图有多个连通分量时的0），
+// 所以求得一组特征对之后，在它们的正交补空间上从新的随机向量再迭代一次（显式收缩），
+// 补空间中最靠端点的特征值仍然优于已求得的第|NEigen|个时，说明遗漏了重特征值的副本，将它加入后继续收缩，
+// 直到补空间中没有更优的特征值。任何一次迭代在MaxIter步内没有收敛时返回ErrNotConverged。
+// 操作L只需矩阵向量乘法，对稀疏的*matrix.SymCSR不会构造稠密矩阵
+func (r *SpecFactorize) lanczos(ctx context.Context, L mat.Symmetric) error {
+	var (
+		n     = L.Symmetric()
+		want  = utils.If(r.NEigen > 0, r.NEigen, -r.NEigen).(int)
+		top   = r.NEigen > 0 // 求L'最大的特征值（L最小的）
+		rng   = utils.NewRand(r.RandomState)
+		pairs []ritzPair // 已求得的特征对，按靠近端点的程度排序，全部用于收缩
+		iters int
+		norm  float64 // ||L||的估计（Ritz值绝对值的最大值）
+	)
+	if utils.If(r.MaxIter < n, r.MaxIter, n).(int) < want {
+		return &ParamError{Field: "MaxIter", Value: r.MaxIter}
+	}
+	better := func(a, b float64) bool { return utils.If(top, a > b, a < b).(bool) }
+
+	for k := want; len(pairs) < n; k = 1 {
+		found, steps, err := r.lanczosRun(ctx, L, k, top, pairs, rng, &norm)
+		iters += steps
+		if err != nil {
+			return err
+		}
+		margin := r.Tol * math.Max(norm, 1)
+		if len(pairs) >= want && !better(found[0].value, pairs[want-1].value+utils.If(top, margin, -margin).(float64)) {
+			break
+		}
+		if len(pairs) >= want && r.Verbose {
+			log.Printf("[Lanczos] repeated eigenvalue %f found by deflation\n", found[0].value)
+		}
+		pairs = append(pairs, found...)
+		sort.SliceStable(pairs, func(i, j int) bool { return better(pairs[i].value, pairs[j].value) })
+	}
+
+	pairs = pairs[:want]
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].value < pairs[j].value })
+	r.eVal = make([]float64, want)
+	r.eVec = mat.NewDense(n, want, nil)
+	res := make([]float64, want)
+	for t, p := range pairs {
+		r.eVal[t], res[t] = p.value, p.residual
+		r.eVec.SetCol(t, p.vector.RawVector().Data)
+	}
+	r.offset = utils.If(top, n-want, 0).(int)
+	r.report = &EigenReport{Solver: LanczosSolver, Iterations: iters, Converged: true, Residuals: res}
+	return nil
+}
+
+// lanczosRun 在locked的正交补空间上做一次Lanczos迭代，求L'在补空间中最靠端点（top为最大一端）的k个Ritz对，
+// 按靠近端点的程度排序返回。逐步扩张Krylov子空间 K_m = span{v, Lv, ..., L^(m-1)v}，每隔一段，
+// 对三对角矩阵T_m做特征分解得到Ritz对(θ, Vs)，其残差为 beta_m * |s_m|，全部满足Tol时停止。
+// 子空间不变（beta≈0）时换一个与已有基正交的随机向量继续扩张。norm为||L||的估计，迭代中更新
+func (r *SpecFactorize) lanczosRun(ctx context.Context, L mat.Symmetric, k int, top bool,
+	locked []ritzPair, rng *rand.Rand, norm *float64) ([]ritzPair, int, error) {
+	var (
+		n        = L.Symmetric()
+		p        = len(locked)
+		maxSteps = utils.If(r.MaxIter < n-p, r.MaxIter, n-p).(int)
+		basis    = make([]*mat.VecDense, p, p+maxSteps) // 前p个为收缩的向量，之后为Krylov子空间的基
+		alpha    []float64
+		beta     []float64
+		w        = mat.NewVecDense(n, nil)
+		ritzVal  []float64
+		ritzVec  *mat.Dense
+		res      []float64
+		lo       int
+		conv     bool
+		m        int
+	)
+	for i, pair := range locked {
+		basis[i] = pair.vector
+	}
+	k = utils.If(k < n-p, k, n-p).(int)
+	checkAt := k // 下一次检查收敛时的子空间维数
+	v := lanczosStart(rng, n, basis)
+
+	for m = 1; m <= maxSteps; m++ {
+		if err := ctx.Err(); err != nil {
+			return nil, m, err
+		}
+		basis = append(basis, v)
+		lanczosMulVec(L, w, v)
+		alpha = append(alpha, mat.Dot(w, v))
+		reorthogonalize(w, basis)
+		b := mat.Norm(w, 2)
+
+		invariant := b <= 1e-12*math.Max(*norm, 1)
+		if m >= checkAt || m == maxSteps || (invariant && m >= k) {
+			checkAt = m + utils.If(m/4 > lanczosCheckStep, m/4, lanczosCheckStep).(int)
+			var err error
+			if ritzVal, ritzVec, err = tridiagonalEigen(alpha, beta); err != nil {
+				return nil, m, err
+			}
+			*norm = math.Max(*norm, math.Max(math.Abs(ritzVal[0]), math.Abs(ritzVal[m-1])))
+			lo = utils.If(top, m-k, 0).(int)
+			res, conv = make([]float64, k), true
+			for t := range res {
+				res[t] = b * math.Abs(ritzVec.At(m-1, lo+t))
+				conv = conv && res[t] <= r.Tol*math.Max(*norm, 1)
+			}
+			if r.Verbose {
+				log.Printf("[Lanczos %d] Max Residual: %e\n", m, res[argmax(res)])
+			}
+			if conv || m == maxSteps {
+				break
+			}
+		}
+		if invariant { // Krylov子空间不再扩张，换一个新的方向
+			beta = append(beta, 0)
+			v = lanczosStart(rng, n, basis)
+			continue
+		}
+		beta = append(beta, b)
+		v = mat.NewVecDense(n, nil)
+		v.ScaleVec(1/b, w)
+	}
+	if !conv {
+		if r.Verbose {
+			log.Printf("[Lanczos] not converged after %d iterations\n", m)
+		}
+		return nil, m, ErrNotConverged
+	}
+
+	// Ritz向量 x = Σ_j s_j * basis[p+j]，直接由基向量累加，不构造n×m的基矩阵
+	found := make([]ritzPair, k)
+	for t := range found {
+		x := mat.NewVecDense(n, nil)
+		for j, u := range basis[p:] {
+			x.AddScaledVec(x, ritzVec.At(j, lo+t), u)
+		}
+		found[t] = ritzPair{value: ritzVal[lo+t], vector: x, residual: res[t]}
+	}
+	if top { // 最大的在前
+		for i, j := 0, k-1; i < j; i, j = i+1, j-1 {
+			found[i], found[j] = found[j], found[i]
+		}
+	}
+	return found, m, nil
+}
+
+// lanczosStart 生成与basis正交的随机单位向量
+func lanczosStart(rng *rand.Rand, n int, basis []*mat.VecDense) *mat.VecDense {
+	v := mat.NewVecDense(n, nil)
+	for {
+		for i := 0; i < n; i++ {
+			v.SetVec(i, rng.Float64()-0.5)
+		}
+		reorthogonalize(v, basis)
+		if norm := mat.Norm(v, 2); norm > 1e-8 {
+			v.ScaleVec(1/norm, v)
+			return v
+		}
+	}
+}
+
+// reorthogonalize 将w对basis做两遍Gram-Schmidt正交化
+func reorthogonalize(w *mat.VecDense, basis []*mat.VecDense) {
+	for pass := 0; pass < 2; pass++ {
+		for _, u := range basis {
+			w.AddScaledVec(w, -mat.Dot(w, u), u)
+		}
+	}
+}
+
+// lanczosMulVec dst = L * x，稀疏矩阵只遍历非零元素
+func lanczosMulVec(L mat.Symmetric, dst, x *mat.VecDense) {
+	if sp, ok := L.(*matrix.SymCSR); ok {
+		sp.MulVecTo(dst, x)
+		return
+	}
+	dst.MulVec(L, x)
+}
+
+// tridiagonalEigen 对角线为alpha、次对角线为beta的三对角矩阵的特征分解（特征值升序），分解失败时返回ErrEigenFactorization
+func tridiagonalEigen(alpha, beta []float64) ([]float64, *mat.Dense, error) {
+	var (
+		m  = len(alpha)
+		T  = mat.NewSymDense(m, nil)
+		es = mat.EigenSym{}
+	)
+	for i := 0; i < m; i++ {
+		T.SetSym(i, i, alpha[i])
+		if i+1 < m {
+			T.SetSym(i, i+1, beta[i])
+		}
+	}
+	if ok := es.Factorize(T, true); !ok {
+		return nil, nil, ErrEigenFactorization
+	}
+	vectors := mat.NewDense(m, m, nil)
+	es.VectorsTo(vectors)
+	return es.Values(nil), vectors, nil
+}
@@ -0,0 +1,16 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/13 21:30
+ */
+
+package metrics
+
+import "github.com/yinyajun/golearn/utils"
+
+var (
+	ErrInvalidArgument = utils.ErrInvalidArgument
+	ErrEmptyInput      = utils.ErrEmptyInput
+)
+
+// ParamError 参数不合法，errors.Is(err, ErrInvalidArgument)成立
+type ParamError = utils.ParamError
@@ -0,0 +1,265 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/14 20:12
+ */
+
+package metrics
+
+import (
+	"math"
+
+	"github.com/yinyajun/golearn/utils"
+	"gonum.org/v1/gonum/mat"
+)
+
+// 聚类外部评价指标：比较两组标签（通常一组为真实标签truth，一组为聚类结果pred）
+// 所有指标都基于列联表计算，标签可以是任意非负整数，两组标签的类别数可以不同
+
+// Contingency 列联表
+type Contingency struct {
+	Table   *mat.Dense // Table(i, j)为truth第i类且pred第j类的样本数
+	RowSums []float64  // truth各类的样本数
+	ColSums []float64  // pred各类的样本数
+	N       float64    // 样本总数
+}
+
+func NewContingency(truth, pred []int) (*Contingency, error) {
+	if len(truth) != len(pred) {
+		return nil, &ParamError{Field: "len(pred)", Value: len(pred)}
+	}
+	rowIndex, r, err := relabel(truth)
+	if err != nil {
+		return nil, err
+	}
+	colIndex, c, err := relabel(pred)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Contingency{
+		Table:   mat.NewDense(r, c, nil),
+		RowSums: make([]float64, r),
+		ColSums: make([]float64, c),
+		N:       float64(len(truth)),
+	}
+	for i := range truth {
+		t.Table.Set(rowIndex[i], colIndex[i], t.Table.At(rowIndex[i], colIndex[i])+1)
+		t.RowSums[rowIndex[i]]++
+		t.ColSums[colIndex[i]]++
+	}
+	return t, nil
+}
+
+// AdjustedRandIndex 调整兰德指数，随机标签的期望为0，完全一致时为1
+func AdjustedRandIndex(truth, pred []int) (float64, error) {
+	t, err := NewContingency(truth, pred)
+	if err != nil {
+		return 0, err
+	}
+	r, c := t.Table.Dims()
+	// 特殊情况：两者都只有一类，或者都是每个样本单独一类
+	if (r == 1 && c == 1) || (r == int(t.N) && c == int(t.N)) {
+		return 1, nil
+	}
+
+	var sumComb, sumRows, sumCols float64
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			sumComb += comb2(t.Table.At(i, j))
+		}
+	}
+	for _, a := range t.RowSums {
+		sumRows += comb2(a)
+	}
+	for _, b := range t.ColSums {
+		sumCols += comb2(b)
+	}
+	expected := sumRows * sumCols / comb2(t.N)
+	mean := (sumRows + sumCols) / 2
+	if mean == expected {
+		return 0, nil
+	}
+	return (sumComb - expected) / (mean - expected), nil
+}
+
+// MutualInformation 互信息（自然对数）
+func MutualInformation(truth, pred []int) (float64, error) {
+	t, err := NewContingency(truth, pred)
+	if err != nil {
+		return 0, err
+	}
+	return t.mutualInfo(), nil
+}
+
+// NormalizedMutualInfo 归一化互信息 MI / ((H(truth) + H(pred)) / 2)
+func NormalizedMutualInfo(truth, pred []int) (float64, error) {
+	t, err := NewContingency(truth, pred)
+	if err != nil {
+		return 0, err
+	}
+	if t.trivial() {
+		return 1, nil
+	}
+	mi := t.mutualInfo()
+	if mi == 0 {
+		return 0, nil
+	}
+	return mi / ((entropy(t.RowSums, t.N) + entropy(t.ColSums, t.N)) / 2), nil
+}
+
+// AdjustedMutualInfo 调整互信息 (MI - E[MI]) / ((H(truth) + H(pred)) / 2 - E[MI])
+// E[MI]为超几何分布假设下随机标签的期望互信息
+func AdjustedMutualInfo(truth, pred []int) (float64, error) {
+	t, err := NewContingency(truth, pred)
+	if err != nil {
+		return 0, err
+	}
+	if t.trivial() {
+		return 1, nil
+	}
+	var (
+		mi    = t.mutualInfo()
+		emi   = t.expectedMutualInfo()
+		mean  = (entropy(t.RowSums, t.N) + entropy(t.ColSums, t.N)) / 2
+		denom = mean - emi
+		eps   = math.Nextafter(1, 2) - 1
+	)
+	if denom < 0 {
+		denom = math.Min(denom, -eps)
+	} else {
+		denom = math.Max(denom, eps)
+	}
+	return (mi - emi) / denom, nil
+}
+
+// HomogeneityCompletenessV 同质性h（每个pred类只包含一个truth类）、
+// 完整性c（每个truth类只被分到一个pred类）以及二者的加权调和平均v（beta越大越看重完整性）
+func HomogeneityCompletenessV(truth, pred []int, beta float64) (h, c, v float64, err error) {
+	t, err := NewContingency(truth, pred)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	var (
+		mi    = t.mutualInfo()
+		hRows = entropy(t.RowSums, t.N)
+		hCols = entropy(t.ColSums, t.N)
+	)
+	h, c = 1, 1
+	if hRows != 0 {
+		h = mi / hRows
+	}
+	if hCols != 0 {
+		c = mi / hCols
+	}
+	if h+c != 0 {
+		v = (1 + beta) * h * c / (beta*h + c)
+	}
+	return h, c, v, nil
+}
+
+// VMeasure beta=1时的V-measure
+func VMeasure(truth, pred []int) (float64, error) {
+	_, _, v, err := HomogeneityCompletenessV(truth, pred, 1)
+	return v, err
+}
+
+// Purity 纯度：每个pred类取其中最多的truth类的样本数，求和后除以样本总数
+func Purity(truth, pred []int) (float64, error) {
+	t, err := NewContingency(truth, pred)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		r, c = t.Table.Dims()
+		sum  float64
+	)
+	for j := 0; j < c; j++ {
+		var m float64
+		for i := 0; i < r; i++ {
+			m = math.Max(m, t.Table.At(i, j))
+		}
+		sum += m
+	}
+	return sum / t.N, nil
+}
+
+// FowlkesMallows 样本对层面precision与recall的几何平均 TP / sqrt((TP+FP)(TP+FN))
+func FowlkesMallows(truth, pred []int) (float64, error) {
+	t, err := NewContingency(truth, pred)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		r, c       = t.Table.Dims()
+		tk, pk, qk = -t.N, -t.N, -t.N
+	)
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			tk += t.Table.At(i, j) * t.Table.At(i, j)
+		}
+	}
+	for _, a := range t.RowSums {
+		qk += a * a
+	}
+	for _, b := range t.ColSums {
+		pk += b * b
+	}
+	if tk == 0 {
+		return 0, nil
+	}
+	return math.Sqrt(tk/pk) * math.Sqrt(tk/qk), nil
+}
+
+// LabelsFromBool 将SpecBisection.Labels()的二分结果转为[]int（true为1，false为0）
+func LabelsFromBool(labels []bool) []int { return utils.BoolsToInts(labels) }
+
+// trivial 两者都只有一类，或者都是每个样本单独一类时，认为两组标签完全一致
+func (t *Contingency) trivial() bool {
+	r, c := t.Table.Dims()
+	return (r == 1 && c == 1) || (r == int(t.N) && c == int(t.N))
+}
+
+func (t *Contingency) mutualInfo() (mi float64) {
+	r, c := t.Table.Dims()
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			if nij := t.Table.At(i, j); nij > 0 {
+				mi += nij / t.N * math.Log(t.N*nij/(t.RowSums[i]*t.ColSums[j]))
+			}
+		}
+	}
+	return math.Max(mi, 0)
+}
+
+// expectedMutualInfo 超几何分布下的期望互信息（Vinh et al., 2010）
+func (t *Contingency) expectedMutualInfo() (emi float64) {
+	var (
+		n      = t.N
+		lgamma = func(x float64) float64 { v, _ := math.Lgamma(x + 1); return v } // log(x!)
+		lgN    = lgamma(n)
+	)
+	for _, a := range t.RowSums {
+		for _, b := range t.ColSums {
+			start := math.Max(1, a+b-n)
+			end := math.Min(a, b)
+			for nij := start; nij <= end; nij++ {
+				term := nij / n * math.Log(n*nij/(a*b))
+				logP := lgamma(a) + lgamma(b) + lgamma(n-a) + lgamma(n-b) -
+					lgN - lgamma(nij) - lgamma(a-nij) - lgamma(b-nij) - lgamma(n-a-b+nij)
+				emi += term * math.Exp(logP)
+			}
+		}
+	}
+	return
+}
+
+func entropy(sizes []float64, n float64) (h float64) {
+	for _, s := range sizes {
+		if s > 0 {
+			h -= s / n * math.Log(s/n)
+		}
+	}
+	return
+}
+
+func comb2(n float64) float64 { return n * (n - 1) / 2 }
@@ -0,0 +1,56 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/14 22:45
+ */
+
+package metrics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestExternalMetrics(t *testing.T) {
+	type metric func(truth, pred []int) (float64, error)
+	cases := []struct {
+		name        string
+		fn          metric
+		truth, pred []int
+		expected    float64
+	}{
+		{"ari", AdjustedRandIndex, []int{0, 0, 1, 1}, []int{0, 0, 1, 2}, 4.0 / 7},
+		{"ari", AdjustedRandIndex, []int{0, 0, 1, 1}, []int{1, 1, 0, 0}, 1},
+		{"ari", AdjustedRandIndex, []int{0, 0, 0, 0}, []int{0, 1, 2, 3}, 0},
+		{"ari", AdjustedRandIndex, []int{0, 0, 0, 1, 1, 1, 2, 2}, []int{0, 0, 1, 1, 2, 2, 2, 2}, 2.0 / 11},
+		{"nmi", NormalizedMutualInfo, []int{0, 0, 1, 1}, []int{0, 0, 1, 2}, 0.8},
+		{"nmi", NormalizedMutualInfo, []int{0, 0, 0, 1, 1, 1, 2, 2}, []int{0, 0, 1, 1, 2, 2, 2, 2}, 0.5300257549140326},
+		{"ami", AdjustedMutualInfo, []int{0, 0, 1, 1}, []int{1, 1, 0, 0}, 1},
+		{"ami", AdjustedMutualInfo, []int{0, 0, 0, 0}, []int{0, 1, 2, 3}, 0},
+		{"ami", AdjustedMutualInfo, []int{0, 0, 0, 1, 1, 1, 2, 2}, []int{0, 0, 1, 1, 2, 2, 2, 2}, 0.27454164973683187},
+		{"v", VMeasure, []int{0, 0, 1, 1}, []int{0, 0, 1, 2}, 0.8},
+		{"v", VMeasure, []int{0, 0, 1, 1}, []int{0, 1, 2, 3}, 2.0 / 3},
+		{"purity", Purity, []int{0, 0, 1, 1, 1}, []int{0, 0, 0, 1, 1}, 0.8},
+		{"fmi", FowlkesMallows, []int{0, 0, 1, 1}, []int{1, 1, 0, 0}, 1},
+		{"fmi", FowlkesMallows, []int{0, 0, 0, 0}, []int{0, 1, 2, 3}, 0},
+		{"fmi", FowlkesMallows, []int{0, 0, 0, 1, 1, 1, 2, 2}, []int{0, 0, 1, 1, 2, 2, 2, 2}, 0.4008918628686366},
+	}
+	for _, c := range cases {
+		score, err := c.fn(c.truth, c.pred)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(score-c.expected) > 1e-9 {
+			t.Errorf("%s(%v, %v) = %f, expected %f", c.name, c.truth, c.pred, score, c.expected)
+		}
+	}
+}
+
+func TestLabelsFromBool(t *testing.T) {
+	score, err := AdjustedRandIndex([]int{0, 0, 1, 1}, LabelsFromBool([]bool{true, true, false, false}))
+	if err != nil || score != 1 {
+		t.Errorf("unexpected score %f, %v", score, err)
+	}
+	if _, err = AdjustedRandIndex([]int{0, 1}, []int{0}); err == nil {
+		t.Errorf("labels of different length should be rejected")
+	}
+}
@@ -0,0 +1,253 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/13 21:37
+ */
+
+package metrics
+
+import (
+	"math"
+
+	"github.com/yinyajun/golearn/matrix"
+	"github.com/yinyajun/golearn/utils"
+	"gonum.org/v1/gonum/mat"
+)
+
+// 聚类内部评价指标（无需真实标签）
+// 基于距离的指标（Silhouette、Dunn）使用matrix.Distances：
+//   d.Dist(i, j)为样本i,j之间的距离，可以由matrix.MetricDist从数据矩阵构造，
+//   也可以由matrix.SymmetricDist从预计算的距离矩阵构造；d.NGoroutines为并发度，d.Filters被忽略
+// 基于中心的指标（DaviesBouldin、CalinskiHarabasz）直接使用数据矩阵，距离为欧式距离
+
+// SilhouetteSamples 每个样本的轮廓系数 s(i) = (b(i)-a(i)) / max(a(i), b(i))
+// a(i)为i到同类其他样本的平均距离，b(i)为i到其他各类平均距离的最小值；单独成类的样本s(i)=0
+func SilhouetteSamples(d *matrix.Distances, labels []int) ([]float64, error) {
+	index, k, err := relabel(labels)
+	if err != nil {
+		return nil, err
+	}
+	n := len(index)
+	if k < 2 || k > n-1 {
+		return nil, &ParamError{Field: "NClusters", Value: k}
+	}
+	if d.NGoroutines < 1 {
+		return nil, &ParamError{Field: "NGoroutines", Value: d.NGoroutines}
+	}
+
+	var (
+		sizes = clusterSizes(index, k)
+		res   = make([]float64, n)
+	)
+	utils.Parallel(n, d.NGoroutines, func(i int) {
+		if sizes[index[i]] == 1 {
+			return
+		}
+		sums := make([]float64, k)
+		for j := 0; j < n; j++ {
+			if i != j {
+				sums[index[j]] += d.Dist(i, j)
+			}
+		}
+		a, b := sums[index[i]]/float64(sizes[index[i]]-1), math.Inf(1)
+		for c := 0; c < k; c++ {
+			if c != index[i] {
+				b = math.Min(b, sums[c]/float64(sizes[c]))
+			}
+		}
+		if m := math.Max(a, b); m > 0 {
+			res[i] = (b - a) / m
+		}
+	})
+	return res, nil
+}
+
+// SilhouetteScore 所有样本轮廓系数的均值，取值[-1, 1]，越大越好
+func SilhouetteScore(d *matrix.Distances, labels []int) (float64, error) {
+	samples, err := SilhouetteSamples(d, labels)
+	if err != nil {
+		return 0, err
+	}
+	var s float64
+	for _, v := range samples {
+		s += v
+	}
+	return s / float64(len(samples)), nil
+}
+
+// Dunn 最小类间距离 / 最大类内直径，越大越好
+// 类间距离为两类样本之间的最短距离，类内直径为类内样本之间的最大距离
+func Dunn(d *matrix.Distances, labels []int) (float64, error) {
+	index, k, err := relabel(labels)
+	if err != nil {
+		return 0, err
+	}
+	if k < 2 {
+		return 0, &ParamError{Field: "NClusters", Value: k}
+	}
+	if d.NGoroutines < 1 {
+		return 0, &ParamError{Field: "NGoroutines", Value: d.NGoroutines}
+	}
+
+	var (
+		n     = len(index)
+		inter = make([]float64, n) // 样本i到其他类的最短距离
+		intra = make([]float64, n) // 样本i到同类样本的最长距离
+	)
+	utils.Parallel(n, d.NGoroutines, func(i int) {
+		inter[i] = math.Inf(1)
+		for j := 0; j < n; j++ {
+			if i == j {
+				continue
+			}
+			if dist := d.Dist(i, j); index[i] == index[j] {
+				intra[i] = math.Max(intra[i], dist)
+			} else {
+				inter[i] = math.Min(inter[i], dist)
+			}
+		}
+	})
+
+	minInter, maxIntra := math.Inf(1), 0.0
+	for i := 0; i < n; i++ {
+		minInter = math.Min(minInter, inter[i])
+		maxIntra = math.Max(maxIntra, intra[i])
+	}
+	if maxIntra == 0 {
+		return math.Inf(1), nil
+	}
+	return minInter / maxIntra, nil
+}
+
+// DaviesBouldin 1/k * sum_i max_{j!=i} (s_i + s_j) / d(c_i, c_j)，越小越好
+// s_i为第i类样本到中心c_i的平均距离
+func DaviesBouldin(X mat.Matrix, labels []int) (float64, error) {
+	centers, index, k, err := centroids(X, labels)
+	if err != nil {
+		return 0, err
+	}
+	if k < 2 {
+		return 0, &ParamError{Field: "NClusters", Value: k}
+	}
+
+	var (
+		scatter = make([]float64, k)
+		sizes   = clusterSizes(index, k)
+		points  = rows(X)
+		res     float64
+	)
+	for i, c := range index {
+		scatter[c] += utils.Euclidean(points.RowView(i), centers.RowView(c))
+	}
+	for c := 0; c < k; c++ {
+		scatter[c] /= float64(sizes[c])
+	}
+
+	for i := 0; i < k; i++ {
+		worst := 0.0
+		for j := 0; j < k; j++ {
+			if i == j {
+				continue
+			}
+			dist := utils.Euclidean(centers.RowView(i), centers.RowView(j))
+			if dist == 0 {
+				worst = math.Inf(1)
+				continue
+			}
+			worst = math.Max(worst, (scatter[i]+scatter[j])/dist)
+		}
+		res += worst
+	}
+	return res / float64(k), nil
+}
+
+// CalinskiHarabasz 类间离散度与类内离散度之比 [B/(k-1)] / [W/(n-k)]，越大越好
+func CalinskiHarabasz(X mat.Matrix, labels []int) (float64, error) {
+	centers, index, k, err := centroids(X, labels)
+	if err != nil {
+		return 0, err
+	}
+	n := len(index)
+	if k < 2 || k > n-1 {
+		return 0, &ParamError{Field: "NClusters", Value: k}
+	}
+
+	var (
+		points  = rows(X)
+		mean, _ = matrix.DenseMean(points, 0)
+		sizes   = clusterSizes(index, k)
+		between float64
+		within  float64
+	)
+	for c := 0; c < k; c++ {
+		between += float64(sizes[c]) * utils.EuclideanSquare(centers.RowView(c), mean)
+	}
+	for i, c := range index {
+		within += utils.EuclideanSquare(points.RowView(i), centers.RowView(c))
+	}
+	if within == 0 {
+		return 1, nil
+	}
+	return between * float64(n-k) / (within * float64(k-1)), nil
+}
+
+// relabel 将任意非负整数标签重新编号为0..k-1
+func relabel(labels []int) (index []int, k int, err error) {
+	if len(labels) == 0 {
+		return nil, 0, ErrEmptyInput
+	}
+	var (
+		ids = make(map[int]int)
+		ok  bool
+	)
+	index = make([]int, len(labels))
+	for i, label := range labels {
+		if label < 0 {
+			return nil, 0, &ParamError{Field: "labels", Value: label}
+		}
+		if index[i], ok = ids[label]; !ok {
+			index[i] = len(ids)
+			ids[label] = index[i]
+		}
+	}
+	return index, len(ids), nil
+}
+
+func clusterSizes(index []int, k int) []int {
+	sizes := make([]int, k)
+	for _, c := range index {
+		sizes[c]++
+	}
+	return sizes
+}
+
+// centroids 计算每一类的中心
+func centroids(X mat.Matrix, labels []int) (centers *mat.Dense, index []int, k int, err error) {
+	if r, _ := X.Dims(); r != len(labels) {
+		return nil, nil, 0, &ParamError{Field: "len(labels)", Value: len(labels)}
+	}
+	if index, k, err = relabel(labels); err != nil {
+		return nil, nil, 0, err
+	}
+	var (
+		points = rows(X)
+		sizes  = clusterSizes(index, k)
+	)
+	_, nFeatures := points.Dims()
+	centers = mat.NewDense(k, nFeatures, nil)
+	for i, c := range index {
+		row := centers.RowView(c).(*mat.VecDense)
+		row.AddVec(row, points.RowView(i))
+	}
+	for c := 0; c < k; c++ {
+		row := centers.RowView(c).(*mat.VecDense)
+		row.ScaleVec(1/float64(sizes[c]), row)
+	}
+	return centers, index, k, nil
+}
+
+func rows(X mat.Matrix) *mat.Dense {
+	if d, ok := X.(*mat.Dense); ok {
+		return d
+	}
+	return mat.DenseCopyOf(X)
+}
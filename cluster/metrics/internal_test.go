@@ -0,0 +1,81 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/13 23:02
+ */
+
+package metrics
+
+import (
+	"math"
+	"testing"
+
+	"github.com/yinyajun/golearn/matrix"
+	"github.com/yinyajun/golearn/utils"
+	"gonum.org/v1/gonum/mat"
+)
+
+// 两个一维的簇：{0, 1, 2}和{10, 11}
+var (
+	points = mat.NewDense(5, 1, []float64{0, 1, 2, 10, 11})
+	labels = []int{0, 0, 0, 1, 1}
+)
+
+func TestSilhouette(t *testing.T) {
+	d := &matrix.Distances{Dist: matrix.MetricDist(points, utils.Euclidean), NGoroutines: 2}
+	samples, err := SilhouetteSamples(d, labels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 样本0: a = (1+2)/2 = 1.5, b = (10+11)/2 = 10.5
+	if math.Abs(samples[0]-(10.5-1.5)/10.5) > 1e-12 {
+		t.Errorf("unexpected silhouette of sample 0: %f", samples[0])
+	}
+
+	// 从预计算的距离矩阵计算，结果应一致
+	dist, err := d.SelfCartesian(utils.Range(0, 5, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	score1, _ := SilhouetteScore(d, labels)
+	score2, _ := SilhouetteScore(&matrix.Distances{Dist: matrix.SymmetricDist(dist), NGoroutines: 1}, labels)
+	if math.Abs(score1-score2) > 1e-12 {
+		t.Errorf("silhouette differs: %f, %f", score1, score2)
+	}
+}
+
+func TestDunn(t *testing.T) {
+	d := &matrix.Distances{Dist: matrix.MetricDist(points, utils.Euclidean), NGoroutines: 2}
+	score, err := Dunn(d, labels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(score-8.0/2.0) > 1e-12 {
+		t.Errorf("unexpected dunn index: %f", score)
+	}
+}
+
+func TestDaviesBouldin(t *testing.T) {
+	score, err := DaviesBouldin(points, labels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// s0 = 2/3, s1 = 1/2, d(c0, c1) = 10.5 - 1
+	if expected := (2.0/3 + 0.5) / 9.5; math.Abs(score-expected) > 1e-12 {
+		t.Errorf("unexpected davies bouldin index: %f, expected %f", score, expected)
+	}
+}
+
+func TestCalinskiHarabasz(t *testing.T) {
+	score, err := CalinskiHarabasz(points, labels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// mean = 4.8, B = 3*3.8^2 + 2*5.7^2, W = 2 + 0.5
+	if expected := (3*3.8*3.8 + 2*5.7*5.7) * 3 / 2.5; math.Abs(score-expected) > 1e-9 {
+		t.Errorf("unexpected calinski harabasz index: %f, expected %f", score, expected)
+	}
+
+	if _, err := CalinskiHarabasz(points, []int{0, 0, 0, 0, 0}); err == nil {
+		t.Errorf("single cluster should be rejected")
+	}
+}
@@ -0,0 +1,197 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/10 20:41
+ */
+
+package cluster
+
+import (
+	"context"
+	"log"
+	"math"
+	"math/rand"
+
+	"github.com/yinyajun/golearn/utils"
+	"gonum.org/v1/gonum/mat"
+)
+
+// MiniBatchKMeans 小批量kmeans（Sculley, 2010）
+// 每轮只采样BatchSize个点，以1/count为各中心的学习率增量更新中心，
+// 适用于一次Fit无法遍历全部数据、或数据以流的形式分块到达的场景
+type MiniBatchKMeans struct {
+	KMeans            // 复用kmeans的参数、kmeans++初始化以及Transform等
+	BatchSize int     // 每个batch的样本数
+	Tol       float64 // 一个batch内中心移动距离平方和小于Tol时提前停止（<=0时不提前停止）
+	counts    []int   // 各中心累计分配到的样本数
+	rng       *rand.Rand
+}
+
+func NewMiniBatchKMeans(NClusters, BatchSize int) *MiniBatchKMeans {
+	m := &MiniBatchKMeans{
+		KMeans:    *NewKMeans(NClusters),
+		BatchSize: BatchSize,
+	}
+	m.MaxIter = 100
+	return m
+}
+
+func (m *MiniBatchKMeans) checkParams(points *mat.Dense) error {
+	if err := m.KMeans.checkParams(points); err != nil {
+		return err
+	}
+	if m.Algorithm == Constrained {
+		return &ParamError{Field: "Algorithm", Value: m.Algorithm}
+	}
+	if m.BatchSize < 1 {
+		return &ParamError{Field: "BatchSize", Value: m.BatchSize}
+	}
+	return nil
+}
+
+// Fit 在X上随机采样batch迭代MaxIter轮，最后将X中所有点分配到最近的中心
+func (m *MiniBatchKMeans) Fit(X mat.Matrix) error {
+	return m.FitContext(context.Background(), X)
+}
+
+// FitContext 同Fit，每个batch之间检查ctx，取消时返回ctx.Err()
+func (m *MiniBatchKMeans) FitContext(ctx context.Context, X mat.Matrix) error {
+	points := asDense(X)
+	if err := m.checkParams(points); err != nil {
+		return err
+	}
+	m.rng = utils.NewRand(m.RandomState)
+	var (
+		n           = m.nSamples(points)
+		initSize    = int(math.Min(float64(n), math.Max(float64(3*m.BatchSize), float64(3*m.NClusters))))
+		validation  = m.sample(points, int(math.Min(float64(n), float64(m.BatchSize))))
+		bestCenters *mat.Dense
+		bestCost    = math.Inf(1)
+		cost        float64
+	)
+
+	// 多次初始化，取在验证batch上cost最小的一次
+	for i := 0; i < m.NInit; i++ {
+		m.initCenters(m.sample(points, initSize), m.rng.Int63())
+		_, cost = m.nearestAll(validation)
+		if cost < bestCost {
+			bestCost, bestCenters = cost, m.centers
+		}
+	}
+	m.centers = bestCenters
+	m.counts = make([]int, m.NClusters)
+
+	for iter := 0; iter < m.MaxIter; iter++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		shift := m.step(m.sample(points, m.BatchSize))
+		if m.Verbose {
+			log.Printf("[Epoch %d] Batch cost: %f, Shift: %f\n", iter, m.cost, shift)
+		}
+		if shift < m.Tol {
+			break
+		}
+	}
+
+	m.labels, m.cost = m.nearestAll(points)
+	m.done = true
+	return nil
+}
+
+func (m *MiniBatchKMeans) FitPredict(X mat.Matrix) ([]int, error) {
+	if err := m.Fit(X); err != nil {
+		return nil, err
+	}
+	return m.Labels(), nil
+}
+
+// PartialFit 用一个batch增量更新中心，首次调用时用该batch做kmeans++初始化
+// 调用后Labels()和Cost()对应的是这个batch
+func (m *MiniBatchKMeans) PartialFit(X mat.Matrix) error {
+	batch := asDense(X)
+	if batch.IsEmpty() {
+		return ErrEmptyInput
+	}
+	if m.NGoroutines < 1 || m.NGoroutines >= 100 {
+		return &ParamError{Field: "NGoroutines", Value: m.NGoroutines}
+	}
+	if m.centers == nil {
+		if m.NClusters <= 1 || m.NClusters > m.nSamples(batch) {
+			return &ParamError{Field: "NClusters", Value: m.NClusters}
+		}
+		m.rng = utils.NewRand(m.RandomState)
+		m.initCenters(batch, m.rng.Int63())
+		m.counts = make([]int, m.NClusters)
+	}
+	if _, nFeatures := batch.Dims(); nFeatures != m.centers.RawMatrix().Cols {
+		return &ParamError{Field: "nFeatures", Value: nFeatures}
+	}
+	m.step(batch)
+	m.done = true
+	return nil
+}
+
+// step 对一个batch做一次小批量更新，返回中心移动距离的平方和
+func (m *MiniBatchKMeans) step(batch *mat.Dense) (shift float64) {
+	var (
+		old = mat.DenseCopyOf(m.centers)
+		lr  float64
+	)
+	m.labels, m.cost = m.nearestAll(batch)
+
+	// center = (1-lr)*center + lr*x
+	for i, class := range m.labels {
+		m.counts[class]++
+		lr = 1 / float64(m.counts[class])
+		center := m.centers.RowView(class).(*mat.VecDense)
+		center.ScaleVec(1-lr, center)
+		center.AddScaledVec(center, lr, batch.RowView(i))
+	}
+	for k := 0; k < m.NClusters; k++ {
+		shift += utils.EuclideanSquare(old.RowView(k), m.centers.RowView(k))
+	}
+	return
+}
+
+// nearest 返回距离x最近的聚类中心及距离平方
+func (m *KMeans) nearest(x mat.Vector) (cluster int, minDist float64) {
+	var dist float64
+	minDist = math.Inf(1)
+	for k := 0; k < m.NClusters; k++ {
+		dist = utils.EuclideanSquare(x, m.centers.RowView(k))
+		if dist < minDist {
+			cluster, minDist = k, dist
+		}
+	}
+	return
+}
+
+// nearestAll 并发计算points中每个点最近的聚类中心，以及总的距离平方和
+func (m *KMeans) nearestAll(points *mat.Dense) ([]int, float64) {
+	var (
+		n      = m.nSamples(points)
+		labels = make([]int, n)
+		dists  = make([]float64, n)
+		cost   float64
+	)
+	m.parallel(n, func(lo, hi int) int {
+		for i := lo; i < hi; i++ {
+			labels[i], dists[i] = m.nearest(points.RowView(i))
+		}
+		return 0
+	})
+	for _, d := range dists {
+		cost += d
+	}
+	return labels, cost
+}
+
+// sample 从points中有放回地随机采样size行
+func (m *MiniBatchKMeans) sample(points *mat.Dense, size int) *mat.Dense {
+	n, nFeatures := points.Dims()
+	res := mat.NewDense(size, nFeatures, nil)
+	for i := 0; i < size; i++ {
+		res.SetRow(i, points.RawRowView(m.rng.Intn(n)))
+	}
+	return res
+}
@@ -0,0 +1,355 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/20 21:18
+ */
+
+package cluster
+
+import (
+	"context"
+	"log"
+	"math"
+	"math/rand"
+	"runtime"
+
+	"github.com/yinyajun/golearn/matrix"
+	"github.com/yinyajun/golearn/utils"
+	"gonum.org/v1/gonum/mat"
+)
+
+const (
+	RandomLandmarks = "random" // 从样本中无放回地随机选取landmark
+	KMeansLandmarks = "kmeans" // 以MiniBatchKMeans的聚类中心作为landmark
+)
+
+// NystromClustering 基于Nyström近似的谱聚类（Fowlkes et al., 2004）
+// 只计算n个样本与m个landmark之间的相似度C(n×m)以及landmark之间的相似度W(m×m)，
+// 以 C W^+ C^T 近似完整的相似度矩阵，估计度并归一化后求近似的谱嵌入，再用kMeans聚类。
+// 内存与n·m成正比，适合无法构造n×n相似度矩阵的大规模数据。
+// 与SpecClustering不同，Fit的输入是数据矩阵（每行一个样本），相似度由Kernel计算
+type NystromClustering struct {
+	NClusters   int           // 聚类数
+	NLandmarks  int           // landmark个数m
+	Landmarks   string        // landmark的选取方式："random", "kmeans"
+	ReducedDim  int           // 谱嵌入的维度（取归一化相似度最大的ReducedDim个特征向量，即归一化L最小的）
+	Kernel      utils.Metric  // 相似度核函数，取值应当非负（例如高斯核）
+	NGoroutines int           // 计算相似度的并发度
+	Verbose     bool          // 冗余模式
+	KMeans      *KMeans       // kMeans实例（降维后用kMeans再聚类）
+	RandomState rand.Source   // 随机源（非nil时覆盖KMeans.RandomState），使聚类结果可复现
+	landmarks   *mat.Dense    // landmark（每行一个）
+	degreeCoef  *mat.VecDense // W^+ C^T 1，新样本的度估计为 c · degreeCoef
+	proj        *mat.Dense    // 新样本归一化后的相似度c到谱嵌入的投影（m×ReducedDim）
+	embedding   *mat.Dense
+	done        bool
+}
+
+func NewNystromClustering(kernel utils.Metric, NClusters, NLandmarks int) *NystromClustering {
+	return &NystromClustering{
+		NClusters:   NClusters,
+		NLandmarks:  NLandmarks,
+		Landmarks:   RandomLandmarks,
+		ReducedDim:  NClusters,
+		Kernel:      kernel,
+		NGoroutines: utils.If(runtime.NumCPU() > 1, runtime.NumCPU()/2, 1).(int),
+		KMeans:      NewKMeans(NClusters),
+	}
+}
+
+func (c *NystromClustering) Fit(X mat.Matrix) error {
+	return c.FitContext(context.Background(), X)
+}
+
+// FitContext 同Fit，在计算相似度、谱嵌入与kMeans的各个阶段检查ctx，取消时返回ctx.Err()
+func (c *NystromClustering) FitContext(ctx context.Context, X mat.Matrix) error {
+	points := asDense(X)
+	if err := c.check(points); err != nil {
+		return err
+	}
+	return c.partialFit(ctx, points)
+}
+
+func (c *NystromClustering) check(points *mat.Dense) error {
+	if points.IsEmpty() {
+		return ErrEmptyInput
+	}
+	n, _ := points.Dims()
+	if c.NClusters <= 1 || c.NClusters >= n {
+		return &ParamError{Field: "NClusters", Value: c.NClusters}
+	}
+	if c.NLandmarks < c.NClusters || c.NLandmarks > n {
+		return &ParamError{Field: "NLandmarks", Value: c.NLandmarks}
+	}
+	if c.Landmarks != RandomLandmarks && c.Landmarks != KMeansLandmarks {
+		return &ParamError{Field: "Landmarks", Value: c.Landmarks}
+	}
+	if c.ReducedDim < 1 || c.ReducedDim > c.NLandmarks {
+		return &ParamError{Field: "ReducedDim", Value: c.ReducedDim}
+	}
+	if c.Kernel == nil {
+		return &ParamError{Field: "Kernel", Value: nil}
+	}
+	if c.NGoroutines < 1 {
+		return &ParamError{Field: "NGoroutines", Value: c.NGoroutines}
+	}
+	if c.KMeans == nil {
+		return &ParamError{Field: "KMeans", Value: nil}
+	}
+	return nil
+}
+
+func (c *NystromClustering) partialFit(ctx context.Context, points *mat.Dense) (err error) {
+	var (
+		n, _ = points.Dims()
+		C, W *mat.Dense
+	)
+	if c.landmarks, err = c.selectLandmarks(ctx, points); err != nil {
+		return err
+	}
+	if C, err = c.similarities(ctx, points); err != nil {
+		return err
+	}
+	if W, err = c.similarities(ctx, c.landmarks); err != nil {
+		return err
+	}
+
+	// 估计度：d = C W^+ C^T 1，landmark的度为 d_L = W W^+ C^T 1
+	var (
+		m      = c.NLandmarks
+		ones   = mat.NewVecDense(n, nil)
+		r      = mat.NewVecDense(m, nil)
+		s      = mat.NewVecDense(m, nil)
+		degree = mat.NewVecDense(n, nil)
+		dl     = mat.NewVecDense(m, nil)
+	)
+	for i := 0; i < n; i++ {
+		ones.SetVec(i, 1)
+	}
+	r.MulVec(C.T(), ones)
+	Winv, err := symPower(W, -1)
+	if err != nil {
+		return err
+	}
+	s.MulVec(Winv, r)
+	degree.MulVec(C, s)
+	dl.MulVec(W, s)
+
+	// 归一化：Ĉ = D^(-1/2) C D_L^(-1/2), Ŵ = D_L^(-1/2) W D_L^(-1/2)
+	scaleRows(C, invSqrt(degree))
+	scaleCols(C, invSqrt(dl))
+	scaleRows(W, invSqrt(dl))
+	scaleCols(W, invSqrt(dl))
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	// 近似的归一化相似度 Ĉ Ŵ^+ Ĉ^T 的特征分解：
+	// S = Ŵ^(-1/2) Ĉ^T Ĉ Ŵ^(-1/2) = U Λ U^T，特征向量为 Ĉ Ŵ^(-1/2) U Λ^(-1/2)
+	WinvSqrt, err := symPower(W, -0.5)
+	if err != nil {
+		return err
+	}
+	var (
+		CtC = mat.NewDense(m, m, nil)
+		S   = mat.NewDense(m, m, nil)
+		es  = mat.EigenSym{}
+		U   = mat.NewDense(m, m, nil)
+		k   = c.ReducedDim
+	)
+	CtC.Mul(C.T(), C)
+	S.Product(WinvSqrt, CtC, WinvSqrt)
+	if ok := es.Factorize(symmetrize(S), true); !ok {
+		return ErrEigenFactorization
+	}
+	values := es.Values(nil)
+	es.VectorsTo(U)
+
+	// 取最大的k个特征值对应的特征向量
+	Uk := mat.DenseCopyOf(U.Slice(0, m, m-k, m))
+	for j := 0; j < k; j++ {
+		lambda := values[m-k+j]
+		scale := 0.0
+		if lambda > 0 {
+			scale = 1 / math.Sqrt(lambda)
+		}
+		col := Uk.ColView(j).(*mat.VecDense)
+		col.ScaleVec(scale, col)
+	}
+	c.proj = mat.NewDense(m, k, nil)
+	c.proj.Mul(WinvSqrt, Uk)
+	c.embedding = mat.NewDense(n, k, nil)
+	c.embedding.Mul(C, c.proj)
+	scaleRows(c.proj, invSqrt(dl)) // 新样本的c只需除以sqrt(d_x)，D_L^(-1/2)并入投影
+	c.degreeCoef = s
+	if c.Verbose {
+		log.Printf("[Nystrom] Top eigenvalues: %v\n", values[m-k:])
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	src := c.KMeans.RandomState
+	if c.RandomState != nil {
+		src = c.RandomState
+	}
+	if err = c.KMeans.checkParams(c.embedding); err != nil {
+		return err
+	}
+	if err = c.KMeans.partialFit(ctx, c.embedding, src); err != nil {
+		return err
+	}
+	c.done = true
+	return nil
+}
+
+// selectLandmarks 选取landmark，NLandmarks等于样本数时所有样本都是landmark
+func (c *NystromClustering) selectLandmarks(ctx context.Context, points *mat.Dense) (*mat.Dense, error) {
+	var (
+		n, nFeatures = points.Dims()
+		rng          = utils.NewRand(c.RandomState)
+	)
+	if c.Landmarks == KMeansLandmarks && c.NLandmarks < n {
+		km := NewMiniBatchKMeans(c.NLandmarks, 10*c.NLandmarks)
+		km.RandomState = rand.NewSource(rng.Int63())
+		km.NGoroutines = c.NGoroutines
+		if err := km.FitContext(ctx, points); err != nil {
+			return nil, err
+		}
+		return mat.DenseCopyOf(km.centers), nil
+	}
+	landmarks := mat.NewDense(c.NLandmarks, nFeatures, nil)
+	for i, idx := range rng.Perm(n)[:c.NLandmarks] {
+		landmarks.SetRow(i, points.RawRowView(idx))
+	}
+	return landmarks, nil
+}
+
+// similarities 计算X的每一行与每个landmark之间的相似度
+func (c *NystromClustering) similarities(ctx context.Context, X *mat.Dense) (*mat.Dense, error) {
+	n, _ := X.Dims()
+	d := &matrix.Distances{
+		Dist: func(i, j int) float64 {
+			return c.Kernel(X.RowView(i), c.landmarks.RowView(j))
+		},
+		NGoroutines: c.NGoroutines,
+	}
+	return d.CartesianContext(ctx, utils.Range(0, n, 1), utils.Range(0, c.NLandmarks, 1))
+}
+
+func (c *NystromClustering) HasFitted() bool { return c.done }
+
+func (c *NystromClustering) FitPredict(X mat.Matrix) ([]int, error) {
+	if err := c.Fit(X); err != nil {
+		return nil, err
+	}
+	return c.Labels(), nil
+}
+
+// Predict X的每一行为新样本，用Nyström扩展将其映射到谱嵌入空间后分配到最近的聚类中心
+func (c *NystromClustering) Predict(X mat.Matrix) []int {
+	utils.Assert(c.HasFitted(), ErrFitHasNotDone)
+	return c.KMeans.Predict(c.Transform(X))
+}
+
+// Transform 新样本的谱嵌入（每行一个样本）
+func (c *NystromClustering) Transform(X mat.Matrix) *mat.Dense {
+	utils.Assert(c.HasFitted(), ErrFitHasNotDone)
+	points := asDense(X)
+	_, nFeatures := points.Dims()
+	utils.Assert(nFeatures == c.landmarks.RawMatrix().Cols, &ParamError{Field: "X.Cols", Value: nFeatures})
+
+	C, err := c.similarities(context.Background(), points)
+	utils.Assert(err == nil, err)
+	var (
+		n, _      = points.Dims()
+		degree    = mat.NewVecDense(n, nil)
+		embedding = mat.NewDense(n, c.ReducedDim, nil)
+	)
+	degree.MulVec(C, c.degreeCoef)
+	scaleRows(C, invSqrt(degree))
+	embedding.Mul(C, c.proj)
+	return embedding
+}
+
+func (c *NystromClustering) NumClusters() int { return c.NClusters }
+
+// Embedding 训练样本的谱嵌入（n×ReducedDim）
+func (c *NystromClustering) Embedding() *mat.Dense {
+	utils.Assert(c.HasFitted(), ErrFitHasNotDone)
+	return c.embedding
+}
+
+func (c *NystromClustering) Labels() []int {
+	utils.Assert(c.HasFitted(), ErrFitHasNotDone)
+	return c.KMeans.Labels()
+}
+
+// symPower 对称矩阵的幂 A^p = Q diag(λ^p) Q^T，λ <= eps * max|λ|的特征值视为0（伪逆）
+func symPower(A *mat.Dense, p float64) (*mat.Dense, error) {
+	var (
+		m, _ = A.Dims()
+		es   = mat.EigenSym{}
+		Q    = mat.NewDense(m, m, nil)
+		res  = mat.NewDense(m, m, nil)
+		eps  = 1e-10
+	)
+	if ok := es.Factorize(symmetrize(A), true); !ok {
+		return nil, ErrEigenFactorization
+	}
+	values := es.Values(nil)
+	es.VectorsTo(Q)
+	max := math.Max(math.Abs(values[0]), math.Abs(values[m-1]))
+	for j, v := range values {
+		if v > eps*max {
+			values[j] = math.Pow(v, p)
+		} else {
+			values[j] = 0
+		}
+	}
+	QL := mat.DenseCopyOf(Q)
+	scaleCols(QL, values)
+	res.Mul(QL, Q.T())
+	return res, nil
+}
+
+// symmetrize 取(A + A^T)/2，消除数值误差带来的不对称
+func symmetrize(A *mat.Dense) *mat.SymDense {
+	m, _ := A.Dims()
+	res := mat.NewSymDense(m, nil)
+	for i := 0; i < m; i++ {
+		for j := i; j < m; j++ {
+			res.SetSym(i, j, (A.At(i, j)+A.At(j, i))/2)
+		}
+	}
+	return res
+}
+
+// invSqrt 逐元素计算1/sqrt(v)，v <= 0时取0
+func invSqrt(v *mat.VecDense) []float64 {
+	res := make([]float64, v.Len())
+	for i := range res {
+		if x := v.AtVec(i); x > 0 {
+			res[i] = 1 / math.Sqrt(x)
+		}
+	}
+	return res
+}
+
+func scaleRows(A *mat.Dense, s []float64) {
+	for i, v := range s {
+		row := A.RawRowView(i)
+		for j := range row {
+			row[j] *= v
+		}
+	}
+}
+
+func scaleCols(A *mat.Dense, s []float64) {
+	r, _ := A.Dims()
+	for i := 0; i < r; i++ {
+		row := A.RawRowView(i)
+		for j, v := range s {
+			row[j] *= v
+		}
+	}
+}
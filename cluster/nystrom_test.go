@@ -0,0 +1,78 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/20 23:02
+ */
+
+package cluster
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/yinyajun/golearn/cluster/metrics"
+	"github.com/yinyajun/golearn/utils"
+	"gonum.org/v1/gonum/mat"
+)
+
+func gaussianKernel(gamma float64) utils.Metric {
+	return func(a, b mat.Vector) float64 {
+		return math.Exp(-gamma * utils.EuclideanSquare(a, b))
+	}
+}
+
+func TestNystromClustering_Fit(t *testing.T) {
+	data := blobs(1000, 2, 4, 7)
+
+	km := NewKMeans(4)
+	km.RandomState = rand.NewSource(1)
+	truth, err := km.FitPredict(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, landmarks := range []string{RandomLandmarks, KMeansLandmarks} {
+		c := NewNystromClustering(gaussianKernel(0.05), 4, 50)
+		c.Landmarks = landmarks
+		c.RandomState = rand.NewSource(2)
+		labels, err := c.FitPredict(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if score, _ := metrics.AdjustedRandIndex(truth, labels); score < 0.95 {
+			t.Errorf("%s: unexpected cluster result, ari=%f", landmarks, score)
+		}
+		if score, _ := metrics.AdjustedRandIndex(labels, c.Predict(data)); score < 0.99 {
+			t.Errorf("%s: prediction disagrees with fit, ari=%f", landmarks, score)
+		}
+	}
+}
+
+func TestNystromClustering_AllLandmarks(t *testing.T) {
+	data := blobs(60, 2, 2, 4)
+
+	// NLandmarks == n时所有样本都是landmark（kmeans方式也不再聚类）
+	for _, landmarks := range []string{RandomLandmarks, KMeansLandmarks} {
+		c := NewNystromClustering(gaussianKernel(0.05), 2, 60)
+		c.Landmarks = landmarks
+		c.RandomState = rand.NewSource(1)
+		if err := c.Fit(data); err != nil {
+			t.Fatalf("%s: %v", landmarks, err)
+		}
+		if c.KMeans.RandomState != nil {
+			t.Fatalf("RandomState should not be written into KMeans")
+		}
+	}
+}
+
+func TestNystromClustering_ParamError(t *testing.T) {
+	data := blobs(100, 2, 2, 1)
+	c := NewNystromClustering(gaussianKernel(1), 2, 200)
+	if err := c.Fit(data); err == nil {
+		t.Errorf("expected error for NLandmarks > n")
+	}
+	c = NewNystromClustering(nil, 2, 10)
+	if err := c.Fit(data); err == nil {
+		t.Errorf("expected error for nil Kernel")
+	}
+}
@@ -0,0 +1,219 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/21 20:46
+ */
+
+package cluster
+
+import (
+	"context"
+	"log"
+	"math"
+
+	"github.com/yinyajun/golearn/matrix"
+	"github.com/yinyajun/golearn/utils"
+	"gonum.org/v1/gonum/mat"
+)
+
+// BisectionNode 递归二分树的节点
+type BisectionNode struct {
+	Index       []int          // 节点包含的样本在原相似度矩阵中的下标
+	Cut         float64        // 该节点被二分时的cut值（ratio cut或normalized cut，叶子节点为0）
+	Label       int            // 叶子节点的类别（非叶子节点为-1）
+	Left, Right *BisectionNode // 二分后的子节点（叶子节点为nil）
+}
+
+func (n *BisectionNode) IsLeaf() bool { return n.Left == nil }
+
+// Leaves 从左到右的所有叶子节点
+func (n *BisectionNode) Leaves() []*BisectionNode {
+	if n.IsLeaf() {
+		return []*BisectionNode{n}
+	}
+	return append(n.Left.Leaves(), n.Right.Leaves()...)
+}
+
+// RecursiveBisection 递归谱二分，得到k类的层次划分
+// 每一步对所有叶子节点用SpecBisection（在其子相似度矩阵上）试探二分，
+// 选择cut值最小的叶子真正二分，直到满足停止条件：
+// 叶子数达到NClusters、或者没有叶子可以二分（子节点小于MinSize，或cut值大于MaxCut）
+type RecursiveBisection struct {
+	Similarities mat.Symmetric // 数据点之间的相似度矩阵（可以是稠密的*mat.SymDense或稀疏的*matrix.SymCSR）
+	NClusters    int           // 目标类别数（<=0时不限制，只由MinSize与MaxCut停止）
+	MinSize      int           // 二分后每个子节点的最小样本数
+	MaxCut       float64       // 二分的cut值大于MaxCut时不二分（<=0时不限制）
+	CutType      string        // ratioCut or nCut
+	Strict       bool          // 每次严格二分
+	Solver       string        // 特征分解方法："dense"或"lanczos"
+	Verbose      bool          // 冗余模式
+	root         *BisectionNode
+	labels       []int
+	nClusters    int
+	done         bool
+}
+
+// bisectionSplit 对某个叶子节点试探二分的结果
+type bisectionSplit struct {
+	left, right []int
+	cut         float64
+}
+
+func NewRecursiveBisection(sim mat.Symmetric, NClusters int) *RecursiveBisection {
+	return &RecursiveBisection{
+		Similarities: sim,
+		NClusters:    NClusters,
+		MinSize:      1,
+		CutType:      RatioCut,
+		Solver:       DenseSolver,
+	}
+}
+
+func (c *RecursiveBisection) Fit(X mat.Matrix) error {
+	return c.FitContext(context.Background(), X)
+}
+
+// FitContext 同Fit，在每次二分之间以及二分内部检查ctx，取消时返回ctx.Err()
+func (c *RecursiveBisection) FitContext(ctx context.Context, X mat.Matrix) error {
+	sim, err := asSymmetric(X)
+	if err != nil {
+		return err
+	}
+	if err = c.check(sim); err != nil {
+		return err
+	}
+	return c.partialFit(ctx, sim)
+}
+
+func (c *RecursiveBisection) check(sim mat.Symmetric) error {
+	n := sim.Symmetric()
+	if n < 2 {
+		return ErrEmptyInput
+	}
+	if c.NClusters > n || (c.NClusters <= 0 && c.MaxCut <= 0) {
+		return &ParamError{Field: "NClusters", Value: c.NClusters}
+	}
+	if c.MinSize < 1 {
+		return &ParamError{Field: "MinSize", Value: c.MinSize}
+	}
+	if c.CutType != RatioCut && c.CutType != NCut {
+		return &ParamError{Field: "CutType", Value: c.CutType}
+	}
+	if c.Solver != DenseSolver && c.Solver != LanczosSolver {
+		return &ParamError{Field: "Solver", Value: c.Solver}
+	}
+	return nil
+}
+
+func (c *RecursiveBisection) partialFit(ctx context.Context, sim mat.Symmetric) error {
+	var (
+		n      = sim.Symmetric()
+		root   = &BisectionNode{Index: utils.Range(0, n, 1), Label: -1}
+		leaves = []*BisectionNode{root}
+		splits = make(map[*BisectionNode]*bisectionSplit)
+	)
+	for c.NClusters <= 0 || len(leaves) < c.NClusters {
+		best := -1
+		for t, leaf := range leaves {
+			sp, ok := splits[leaf]
+			if !ok {
+				var err error
+				if sp, err = c.split(ctx, sim, leaf); err != nil {
+					return err
+				}
+				splits[leaf] = sp
+			}
+			if sp != nil && (best < 0 || sp.cut < splits[leaves[best]].cut) {
+				best = t
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		leaf, sp := leaves[best], splits[leaves[best]]
+		leaf.Cut = sp.cut
+		leaf.Left = &BisectionNode{Index: sp.left, Label: -1}
+		leaf.Right = &BisectionNode{Index: sp.right, Label: -1}
+		delete(splits, leaf)
+		// 原地替换，保持leaves从左到右的顺序
+		leaves = append(leaves[:best], append([]*BisectionNode{leaf.Left, leaf.Right}, leaves[best+1:]...)...)
+		if c.Verbose {
+			log.Printf("[Bisection %d] Size: %d -> %d + %d, Cut: %f\n",
+				len(leaves)-1, len(leaf.Index), len(sp.left), len(sp.right), sp.cut)
+		}
+	}
+
+	c.labels = make([]int, n)
+	for label, leaf := range leaves {
+		leaf.Label = label
+		for _, i := range leaf.Index {
+			c.labels[i] = label
+		}
+	}
+	c.root, c.nClusters = root, len(leaves)
+	c.done = true
+	return nil
+}
+
+// split 在叶子节点的子相似度矩阵上试探二分，不满足MinSize或MaxCut时返回nil
+func (c *RecursiveBisection) split(ctx context.Context, sim mat.Symmetric, leaf *BisectionNode) (*bisectionSplit, error) {
+	if len(leaf.Index) < 2*c.MinSize {
+		return nil, nil
+	}
+	var (
+		sub = matrix.SubSymmetric(sim, leaf.Index)
+		b   = NewSpecBisection(sub)
+		sp  = &bisectionSplit{}
+	)
+	b.CutType, b.Strict, b.Solver, b.Verbose = c.CutType, c.Strict, c.Solver, c.Verbose
+	if err := b.partialFit(ctx, sub); err != nil {
+		return nil, err
+	}
+	for t, label := range b.labels {
+		if label {
+			sp.left = append(sp.left, leaf.Index[t])
+		} else {
+			sp.right = append(sp.right, leaf.Index[t])
+		}
+	}
+	sp.cut = CutValue(sub, b.labels, c.CutType)
+	if len(sp.left) < c.MinSize || len(sp.right) < c.MinSize || math.IsInf(sp.cut, 1) ||
+		(c.MaxCut > 0 && sp.cut > c.MaxCut) {
+		return nil, nil
+	}
+	return sp, nil
+}
+
+func (c *RecursiveBisection) HasFitted() bool { return c.done }
+
+func (c *RecursiveBisection) FitPredict(X mat.Matrix) ([]int, error) {
+	if err := c.Fit(X); err != nil {
+		return nil, err
+	}
+	return c.Labels(), nil
+}
+
+// Predict X的每一行为新样本与训练样本之间的相似度，新样本归入平均相似度最大的叶子
+func (c *RecursiveBisection) Predict(X mat.Matrix) []int {
+	return predictBySimilarity(X, c.Labels(), c.nClusters)
+}
+
+// NumClusters 训练后为实际得到的叶子数（可能因MinSize、MaxCut而少于NClusters）
+func (c *RecursiveBisection) NumClusters() int {
+	if c.HasFitted() {
+		return c.nClusters
+	}
+	return c.NClusters
+}
+
+// Labels 叶子节点从左到右编号得到的类别
+func (c *RecursiveBisection) Labels() []int {
+	utils.Assert(c.HasFitted(), ErrFitHasNotDone)
+	return c.labels
+}
+
+// Tree 二分树的根节点
+func (c *RecursiveBisection) Tree() *BisectionNode {
+	utils.Assert(c.HasFitted(), ErrFitHasNotDone)
+	return c.root
+}
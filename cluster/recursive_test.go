@@ -0,0 +1,89 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/21 23:15
+ */
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/yinyajun/golearn/cluster/metrics"
+	"github.com/yinyajun/golearn/matrix"
+	"gonum.org/v1/gonum/mat"
+)
+
+// cliques nGroups个大小为size的团，相邻的团之间由一条权重为bridge的弱边相连
+func cliques(nGroups, size int, bridge float64) (*matrix.SymCSR, []int) {
+	var (
+		rows, cols []int
+		vals       []float64
+		truth      = make([]int, nGroups*size)
+	)
+	for g := 0; g < nGroups; g++ {
+		for i := g * size; i < (g+1)*size; i++ {
+			truth[i] = g
+			for j := i + 1; j < (g+1)*size; j++ {
+				rows, cols = append(rows, i), append(cols, j)
+				vals = append(vals, 1)
+			}
+		}
+		if g > 0 {
+			rows, cols = append(rows, g*size-1), append(cols, g*size)
+			vals = append(vals, bridge)
+		}
+	}
+	return matrix.NewSymCSR(nGroups*size, rows, cols, vals), truth
+}
+
+func TestRecursiveBisection_Fit(t *testing.T) {
+	sim, truth := cliques(4, 8, 0.1)
+
+	for _, sim := range []mat.Symmetric{sim, sim.ToSymDense()} {
+		c := NewRecursiveBisection(sim, 4)
+		labels, err := c.FitPredict(sim)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if score, _ := metrics.AdjustedRandIndex(truth, labels); score != 1 {
+			t.Fatalf("unexpected labels %v", labels)
+		}
+		var (
+			tree   = c.Tree()
+			leaves = tree.Leaves()
+		)
+		if len(leaves) != 4 || tree.IsLeaf() || tree.Cut <= 0 {
+			t.Fatalf("unexpected tree %+v", tree)
+		}
+		for i, leaf := range leaves {
+			if leaf.Label != i || len(leaf.Index) != 8 || leaf.Cut != 0 {
+				t.Fatalf("unexpected leaf %+v", leaf)
+			}
+		}
+	}
+}
+
+func TestRecursiveBisection_Stop(t *testing.T) {
+	sim, truth := cliques(4, 6, 0.05)
+
+	// 不限制类别数，只切割弱边
+	c := NewRecursiveBisection(sim, 0)
+	c.MaxCut = 0.1
+	labels, err := c.FitPredict(sim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score, _ := metrics.AdjustedRandIndex(truth, labels); score != 1 || c.NumClusters() != 4 {
+		t.Fatalf("unexpected labels %v", labels)
+	}
+
+	// 每个子节点至少7个样本，团无法再分，只能二分一次
+	c = NewRecursiveBisection(sim, 4)
+	c.MinSize = 7
+	if _, err = c.FitPredict(sim); err != nil {
+		t.Fatal(err)
+	}
+	if c.NumClusters() != 2 {
+		t.Fatalf("expected 2 clusters, got %d", c.NumClusters())
+	}
+}
@@ -0,0 +1,142 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/22 20:31
+ */
+
+package cluster
+
+import (
+	"context"
+	"log"
+	"math"
+
+	"github.com/yinyajun/golearn/matrix"
+	"github.com/yinyajun/golearn/utils"
+	"gonum.org/v1/gonum/mat"
+)
+
+// defaultRefineImbalance 非Strict且未设置Imbalance时FM允许的不平衡度，即55/45
+const defaultRefineImbalance = 0.1
+
+// refine Fiduccia-Mattheyses局部优化（Fiduccia & Mattheyses, 1982）
+// 以当前二分为起点，每一轮(pass)所有点解锁，反复把增益最大且不破坏平衡的点移到另一侧并锁定，
+// 记录累计增益最大且满足平衡约束的前缀，回滚之后的移动；某一轮没有正增益时停止。
+// 增益为移动后cut权重的减少量（max-cut时为增加量），用两侧各一个索引大顶堆维护，
+// 每次移动只更新相邻点的增益，一轮的复杂度为O(nnz·log n)。
+// 平衡约束：每一侧的权重不超过balanceLimit且不为空，移动过程中允许超出一个节点的最大权重以便两侧交换；
+// Strict时沿用balance的约束，否则Imbalance为0时使用defaultRefineImbalance
+func (c *SpecBisection) refine(ctx context.Context, sim mat.Symmetric) error {
+	imbalance := c.Imbalance
+	if imbalance == 0 && !c.Strict {
+		imbalance = defaultRefineImbalance
+	}
+	limit := c.balanceLimit(imbalance)
+	for pass := 0; pass < c.MaxPasses; pass++ {
+		gain, err := c.fmPass(ctx, sim, limit)
+		if err != nil {
+			return err
+		}
+		if c.Verbose {
+			log.Printf("[FM %d] Gain: %f\n", pass, gain)
+		}
+		if !(gain > 1e-12) {
+			break
+		}
+	}
+	return nil
+}
+
+// fmPass FM的一轮，返回保留的移动带来的总增益（没有满足平衡约束的前缀时为-Inf，不做任何移动）
+func (c *SpecBisection) fmPass(ctx context.Context, sim mat.Symmetric, limit float64) (float64, error) {
+	var (
+		n      = len(c.labels)
+		sign   = utils.If(c.MinCut, 1.0, -1.0).(float64)
+		gain   = make([]float64, n)
+		locked = make([]bool, n)
+		heaps  = [2]*utils.IndexMaxHeap{utils.NewIndexMaxHeap(n), utils.NewIndexMaxHeap(n)}
+		weight [2]float64
+		count  [2]int
+		slack  float64 // 节点的最大权重
+		side   = func(i int) int { return utils.If(c.labels[i], 1, 0).(int) }
+		moves  []int
+	)
+	for i := 0; i < n; i++ {
+		weight[side(i)] += c.weight(i)
+		count[side(i)]++
+		slack = math.Max(slack, c.weight(i))
+		matrix.DoRowNonZero(sim, i, func(j int, v float64) {
+			if j == i {
+				return
+			}
+			if c.labels[j] != c.labels[i] {
+				gain[i] += sign * v
+			} else {
+				gain[i] -= sign * v
+			}
+		})
+		heaps[side(i)].Push(i, gain[i])
+	}
+
+	var (
+		balanced = func() bool { return weight[0] <= limit && weight[1] <= limit }
+		cum      float64
+		bestCum  = utils.If(balanced(), 0.0, math.Inf(-1)).(float64)
+		bestLen  = utils.If(balanced(), 0, -1).(int)
+	)
+	for len(moves) < n {
+		if len(moves)%checkInterval == 0 && ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		// 从s侧移出的条件：s侧移出后不为空，且另一侧移入后不超过limit+slack或者s侧是较重的一侧
+		from, top := -1, math.Inf(-1)
+		for s := 0; s < 2; s++ {
+			if heaps[s].Len() == 0 || count[s] < 2 {
+				continue
+			}
+			v, g := heaps[s].Max()
+			if weight[1-s]+c.weight(v) > limit+slack && weight[s] <= weight[1-s] {
+				continue
+			}
+			if from < 0 || g > top || (g == top && weight[s] > weight[from]) {
+				from, top = s, g
+			}
+		}
+		if from < 0 {
+			break
+		}
+
+		v, g := heaps[from].Max()
+		heaps[from].Remove(v)
+		locked[v] = true
+		c.labels[v] = !c.labels[v]
+		weight[from] -= c.weight(v)
+		weight[1-from] += c.weight(v)
+		count[from]--
+		count[1-from]++
+		cum += g
+		moves = append(moves, v)
+		matrix.DoRowNonZero(sim, v, func(u int, w float64) {
+			if u == v || locked[u] {
+				return
+			}
+			if c.labels[u] == c.labels[v] { // 原来的外部边变为内部边
+				gain[u] -= 2 * sign * w
+			} else {
+				gain[u] += 2 * sign * w
+			}
+			heaps[side(u)].Update(u, gain[u])
+		})
+		if balanced() && cum > bestCum+1e-12 {
+			bestCum, bestLen = cum, len(moves)
+		}
+	}
+
+	// 回滚最优前缀之后的移动
+	if bestLen < 0 {
+		bestLen = 0
+	}
+	for _, v := range moves[bestLen:] {
+		c.labels[v] = !c.labels[v]
+	}
+	return bestCum, nil
+}
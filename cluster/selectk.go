@@ -0,0 +1,314 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/16 20:53
+ */
+
+package cluster
+
+import (
+	"context"
+	"log"
+	"math"
+	"math/rand"
+	"runtime"
+
+	"github.com/yinyajun/golearn/cluster/metrics"
+	"github.com/yinyajun/golearn/matrix"
+	"github.com/yinyajun/golearn/utils"
+	"gonum.org/v1/gonum/mat"
+)
+
+const (
+	Elbow        = "elbow"
+	GapStatistic = "gap"
+	Silhouette   = "silhouette"
+)
+
+// SelectK 在[KMin, KMax]范围内为聚类器选择类别数k
+// 对每个k训练一次聚类器，记录类内距离平方和（elbow）、gap statistic以及平均轮廓系数，
+// 按Criterion给出推荐的k。聚类器Fit的输入必须是数据矩阵（每行一个样本），
+// 对预计算相似度矩阵的谱聚类请使用SpecFactorize.EigenGap
+type SelectK struct {
+	KMin         int                   // 最小的k（>=2）
+	KMax         int                   // 最大的k
+	Criterion    string                // 推荐k的准则："elbow", "gap", "silhouette"
+	NRefs        int                   // gap statistic中均匀分布参考数据集的个数（为0时不计算gap statistic）
+	NGoroutines  int                   // 计算轮廓系数的并发度
+	Verbose      bool                  // 冗余模式
+	RandomState  rand.Source           // 生成参考数据集的随机源
+	NewClusterer func(k int) Clusterer // 构造类别数为k的聚类器
+	curve        *KCurve
+	k            int
+	done         bool
+}
+
+// KCurve 各个k对应的评价指标
+type KCurve struct {
+	Ks          []int
+	Inertias    []float64 // 类内距离平方和
+	Gaps        []float64 // gap statistic: E[log(W*_k)] - log(W_k)
+	GapErrs     []float64 // gap statistic的标准误差 s_k = sd_k * sqrt(1 + 1/NRefs)
+	Silhouettes []float64 // 平均轮廓系数
+}
+
+func NewSelectK(KMin, KMax int, newClusterer func(k int) Clusterer) *SelectK {
+	return &SelectK{
+		KMin:         KMin,
+		KMax:         KMax,
+		Criterion:    Silhouette,
+		NRefs:        10,
+		NGoroutines:  utils.If(runtime.NumCPU() > 1, runtime.NumCPU()/2, 1).(int),
+		NewClusterer: newClusterer,
+	}
+}
+
+func (s *SelectK) check(points *mat.Dense) error {
+	n, _ := points.Dims()
+	if n == 0 {
+		return ErrEmptyInput
+	}
+	if s.KMin < 2 {
+		return &ParamError{Field: "KMin", Value: s.KMin}
+	}
+	if s.KMax < s.KMin || s.KMax >= n {
+		return &ParamError{Field: "KMax", Value: s.KMax}
+	}
+	if s.NRefs < 0 || (s.NRefs == 0 && s.Criterion == GapStatistic) {
+		return &ParamError{Field: "NRefs", Value: s.NRefs}
+	}
+	if s.NGoroutines < 1 {
+		return &ParamError{Field: "NGoroutines", Value: s.NGoroutines}
+	}
+	if s.Criterion != Elbow && s.Criterion != GapStatistic && s.Criterion != Silhouette {
+		return &ParamError{Field: "Criterion", Value: s.Criterion}
+	}
+	if s.NewClusterer == nil {
+		return &ParamError{Field: "NewClusterer", Value: nil}
+	}
+	return nil
+}
+
+func (s *SelectK) Fit(X mat.Matrix) error {
+	return s.FitContext(context.Background(), X)
+}
+
+// FitContext 同Fit，每次训练聚类器之间检查ctx，取消时返回ctx.Err()
+func (s *SelectK) FitContext(ctx context.Context, X mat.Matrix) error {
+	points := asDense(X)
+	if err := s.check(points); err != nil {
+		return err
+	}
+
+	var (
+		refs  = s.references(points)
+		curve = &KCurve{}
+		dist  = &matrix.Distances{Dist: matrix.MetricDist(points, utils.Euclidean), NGoroutines: s.NGoroutines}
+	)
+	for k := s.KMin; k <= s.KMax; k++ {
+		labels, err := s.fit(ctx, k, points)
+		if err != nil {
+			return err
+		}
+		inertia := withinSS(points, labels)
+		silhouette, err := metrics.SilhouetteScore(dist, labels)
+		if err != nil {
+			return err
+		}
+		curve.Ks = append(curve.Ks, k)
+		curve.Inertias = append(curve.Inertias, inertia)
+		curve.Silhouettes = append(curve.Silhouettes, silhouette)
+
+		if len(refs) > 0 {
+			gap, gapErr, err := s.gap(ctx, k, inertia, refs)
+			if err != nil {
+				return err
+			}
+			curve.Gaps = append(curve.Gaps, gap)
+			curve.GapErrs = append(curve.GapErrs, gapErr)
+		}
+		if s.Verbose {
+			log.Printf("[K %d] Inertia: %f, Silhouette: %f\n", k, inertia, silhouette)
+		}
+	}
+
+	s.curve = curve
+	switch s.Criterion {
+	case Elbow:
+		s.k = curve.ElbowK()
+	case GapStatistic:
+		s.k = curve.GapK()
+	case Silhouette:
+		s.k = curve.SilhouetteK()
+	}
+	s.done = true
+	return nil
+}
+
+func (s *SelectK) HasFitted() bool { return s.done }
+
+// K 推荐的类别数
+func (s *SelectK) K() int {
+	utils.Assert(s.HasFitted(), ErrFitHasNotDone)
+	return s.k
+}
+
+// Curve 各个k对应的完整评价曲线
+func (s *SelectK) Curve() *KCurve {
+	utils.Assert(s.HasFitted(), ErrFitHasNotDone)
+	return s.curve
+}
+
+func (s *SelectK) fit(ctx context.Context, k int, points *mat.Dense) ([]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.NewClusterer(k).FitPredict(points)
+}
+
+// gap 计算gap statistic及其标准误差
+func (s *SelectK) gap(ctx context.Context, k int, inertia float64, refs []*mat.Dense) (gap, gapErr float64, err error) {
+	var (
+		logW = make([]float64, len(refs))
+		mean float64
+		sd   float64
+	)
+	for b, ref := range refs {
+		labels, err := s.fit(ctx, k, ref)
+		if err != nil {
+			return 0, 0, err
+		}
+		logW[b] = math.Log(withinSS(ref, labels))
+		mean += logW[b]
+	}
+	mean /= float64(len(refs))
+	for _, w := range logW {
+		sd += (w - mean) * (w - mean)
+	}
+	sd = math.Sqrt(sd / float64(len(refs)))
+	return mean - math.Log(inertia), sd * math.Sqrt(1+1/float64(len(refs))), nil
+}
+
+// references 在数据的包围盒内均匀生成NRefs个与points同样大小的参考数据集
+func (s *SelectK) references(points *mat.Dense) []*mat.Dense {
+	var (
+		rng          = utils.NewRand(s.RandomState)
+		n, nFeatures = points.Dims()
+		lo           = make([]float64, nFeatures)
+		hi           = make([]float64, nFeatures)
+		refs         = make([]*mat.Dense, s.NRefs)
+	)
+	for j := 0; j < nFeatures; j++ {
+		lo[j], hi[j] = math.Inf(1), math.Inf(-1)
+		for i := 0; i < n; i++ {
+			lo[j] = math.Min(lo[j], points.At(i, j))
+			hi[j] = math.Max(hi[j], points.At(i, j))
+		}
+	}
+	for b := range refs {
+		refs[b] = mat.NewDense(n, nFeatures, nil)
+		for i := 0; i < n; i++ {
+			for j := 0; j < nFeatures; j++ {
+				refs[b].Set(i, j, lo[j]+rng.Float64()*(hi[j]-lo[j]))
+			}
+		}
+	}
+	return refs
+}
+
+// ElbowK 肘部法则：对log(inertia)曲线归一化后，取离首尾两点连线最远的k
+// （取对数是为了避免k较小时的陡降掩盖真正的拐点）
+func (c *KCurve) ElbowK() int {
+	var (
+		n      = len(c.Ks)
+		logW   = make([]float64, n)
+		best   = 0
+		maxGap = math.Inf(-1)
+	)
+	if n < 3 {
+		return c.Ks[0]
+	}
+	for i, w := range c.Inertias {
+		logW[i] = math.Log(math.Max(w, math.SmallestNonzeroFloat64))
+	}
+	x0, y0 := float64(c.Ks[0]), logW[0]
+	x1, y1 := float64(c.Ks[n-1]), logW[n-1]
+	for i := 0; i < n; i++ {
+		// 归一化到[0,1]后，点在首尾连线下方的距离
+		x := (float64(c.Ks[i]) - x0) / (x1 - x0)
+		y := 0.0
+		if y0 != y1 {
+			y = (logW[i] - y1) / (y0 - y1)
+		}
+		if d := (1 - x) - y; d > maxGap {
+			best, maxGap = i, d
+		}
+	}
+	return c.Ks[best]
+}
+
+// GapK 最小的满足Gap(k) >= Gap(k+1) - s_{k+1}的k（Tibshirani et al., 2001），不存在时取Gap最大的k
+func (c *KCurve) GapK() int {
+	if len(c.Gaps) == 0 {
+		return c.Ks[0]
+	}
+	for i := 0; i+1 < len(c.Gaps); i++ {
+		if c.Gaps[i] >= c.Gaps[i+1]-c.GapErrs[i+1] {
+			return c.Ks[i]
+		}
+	}
+	return c.Ks[argmax(c.Gaps)]
+}
+
+// SilhouetteK 平均轮廓系数最大的k
+func (c *KCurve) SilhouetteK() int {
+	return c.Ks[argmax(c.Silhouettes)]
+}
+
+// EigenGap 特征间隙启发式：L的特征值升序为λ_1 <= λ_2 <= ...，在k <= maxK中取λ_{k+1} - λ_k最大的k
+// gaps[i]为λ_{i+2} - λ_{i+1}（即k = i+1时的间隙）；使用lanczos时NEigen至少为maxK+1
+func (r *SpecFactorize) EigenGap(maxK int) (k int, gaps []float64) {
+	utils.Assert(r.HasFitted(), ErrFitHasNotDone)
+	n := r.eVec.RawMatrix().Rows
+	utils.Assert(1 <= maxK && maxK < n && r.available(n-maxK-1, n), &ParamError{Field: "maxK", Value: maxK})
+
+	// 分解的是L' = -L，L的第i小特征值为-eVal[n-1-i]（lanczos只求部分特征值，需减去offset）
+	lambda := func(i int) float64 { return -r.eVal[n-1-i-r.offset] }
+	gaps = make([]float64, maxK)
+	for i := 0; i < maxK; i++ {
+		gaps[i] = lambda(i+1) - lambda(i)
+	}
+	return argmax(gaps) + 1, gaps
+}
+
+// withinSS 类内距离平方和
+func withinSS(points *mat.Dense, labels []int) (w float64) {
+	var (
+		_, nFeatures = points.Dims()
+		sums         = make(map[int]*mat.VecDense)
+		cnt          = make(map[int]int)
+	)
+	for i, label := range labels {
+		if _, ok := sums[label]; !ok {
+			sums[label] = mat.NewVecDense(nFeatures, nil)
+		}
+		sums[label].AddVec(sums[label], points.RowView(i))
+		cnt[label]++
+	}
+	for label, sum := range sums {
+		sum.ScaleVec(1/float64(cnt[label]), sum)
+	}
+	for i, label := range labels {
+		w += utils.EuclideanSquare(points.RowView(i), sums[label])
+	}
+	return
+}
+
+func argmax(arr []float64) int {
+	best := 0
+	for i := range arr {
+		if arr[i] > arr[best] {
+			best = i
+		}
+	}
+	return best
+}
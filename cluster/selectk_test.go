@@ -0,0 +1,53 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/16 23:10
+ */
+
+package cluster
+
+import (
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestSelectK(t *testing.T) {
+	data := blobs(200, 2, 4, 11)
+
+	for _, criterion := range []string{Elbow, GapStatistic, Silhouette} {
+		s := NewSelectK(2, 7, func(k int) Clusterer {
+			m := NewKMeans(k)
+			m.RandomState = rand.NewSource(1)
+			return m
+		})
+		s.Criterion = criterion
+		s.NRefs = 5
+		s.RandomState = rand.NewSource(2)
+		if err := s.Fit(data); err != nil {
+			t.Fatal(err)
+		}
+		if s.K() != 4 {
+			t.Errorf("%s: expected k=4, got %d, curve: %+v", criterion, s.K(), s.Curve())
+		}
+	}
+}
+
+func TestSpecFactorize_EigenGap(t *testing.T) {
+	// 三个互不相连的团，L有3个0特征值
+	sim := mat.NewSymDense(9, nil)
+	for g := 0; g < 3; g++ {
+		for i := 3 * g; i < 3*g+3; i++ {
+			for j := i + 1; j < 3*g+3; j++ {
+				sim.SetSym(i, j, 1)
+			}
+		}
+	}
+	fac := NewSpecFactorize(false, false)
+	if err := fac.Fit(sim); err != nil {
+		t.Fatal(err)
+	}
+	if k, gaps := fac.EigenGap(5); k != 3 {
+		t.Errorf("expected k=3, got %d, gaps: %v", k, gaps)
+	}
+}
@@ -6,84 +6,173 @@
 package cluster
 
 import (
+	"context"
+	"math/rand"
+
+	"github.com/yinyajun/golearn/matrix"
 	"github.com/yinyajun/golearn/utils"
 	"gonum.org/v1/gonum/mat"
 )
 
 // SpecClustering 谱聚类
 type SpecClustering struct {
-	Similarities *mat.SymDense // 数据点之间的相似度矩阵
+	Similarities mat.Symmetric // 数据点之间的相似度矩阵（可以是稠密的*mat.SymDense或稀疏的*matrix.SymCSR）
 	NClusters    int           // 聚类数
 	ReducedDim   int           // 谱分解后的维度（ReducedDim > 0为优化min—cut，ReducedDim < 0为max-cut）
 	CutType      string        // ratioCut or nCut
+	Laplacian    string        // 拉普拉斯矩阵类型（为空时由CutType决定：ratio_cut为unnormalized，n_cut为symmetric）
+	RowNormalize bool          // kMeans之前将降维后的每一行归一化为单位向量（Ng, Jordan & Weiss, 2001）
+	AssignLabels string        // 由谱嵌入得到类别的方法："kmeans"、"discretize"或"cluster_qr"（后两者是确定的，只用前NClusters个特征向量）
+	Solver       string        // 特征分解方法："dense"或"lanczos"（只求ReducedDim个特征向量，适合大规模稀疏相似度矩阵）
 	Verbose      bool          // 冗余模式
 	KMeans       *KMeans       // kMeans实例（降维后用kMeans再聚类）
+	RandomState  rand.Source   // 随机源（非nil时覆盖KMeans.RandomState），使聚类结果可复现
+	labels       []int
 	done         bool
 }
 
-func NewSpectralClustering(sim *mat.SymDense, NClusters int) *SpecClustering {
+func NewSpectralClustering(sim mat.Symmetric, NClusters int) *SpecClustering {
 	c := &SpecClustering{
 		Similarities: sim,
 		NClusters:    NClusters,
 		ReducedDim:   3 * NClusters,
 		CutType:      "ratio_cut",
+		AssignLabels: KMeansAssign,
+		Solver:       DenseSolver,
 		KMeans:       NewKMeans(NClusters),
 	}
 	return c
 }
 
-func (c *SpecClustering) Fit(X mat.Symmetric) error {
-	c.check()
-	return c.partialFit(X)
+func (c *SpecClustering) Fit(X mat.Matrix) error {
+	return c.FitContext(context.Background(), X)
+}
+
+// FitContext 同Fit，在特征分解与kMeans的各个阶段检查ctx，取消时返回ctx.Err()
+func (c *SpecClustering) FitContext(ctx context.Context, X mat.Matrix) error {
+	sim, err := asSymmetric(X)
+	if err != nil {
+		return err
+	}
+	if err = c.check(sim); err != nil {
+		return err
+	}
+	return c.partialFit(ctx, sim)
 }
 
-func (c *SpecClustering) partialFit(sim mat.Symmetric) error {
+func (c *SpecClustering) partialFit(ctx context.Context, sim mat.Symmetric) error {
 	var (
 		err     error
 		reduced *mat.Dense
-		fac     *SpecFactorize
+		fac     = NewSpecFactorize(c.Verbose, false)
 	)
-	switch c.CutType {
-	case RatioCut:
-		fac = NewSpecFactorize(c.Verbose, false)
-	case NCut:
-		fac = NewSpecFactorize(c.Verbose, true)
-	default:
-		panic(ErrInvalidArgument)
-	}
-	if err = fac.partialFit(sim); err != nil {
+	fac.Laplacian, fac.Solver, fac.NEigen, fac.RandomState = c.laplacian(), c.Solver, c.ReducedDim, c.RandomState
+	if err = fac.partialFit(ctx, factorizeInput(sim, c.Solver)); err != nil {
 		return err
 	}
-	reduced = fac.SmallKEigenVectors(c.ReducedDim)
-	if err = c.KMeans.partialFit(reduced); err != nil {
-		return err
+	switch c.AssignLabels {
+	case DiscretizeAssign, ClusterQRAssign:
+		// ReducedDim的符号决定取L最小（>0）或最大（<0）的NClusters个特征向量
+		k := utils.If(c.ReducedDim > 0, c.NClusters, -c.NClusters).(int)
+		if c.AssignLabels == DiscretizeAssign {
+			c.labels, err = Discretize(fac.SmallKEigenVectors(k))
+		} else {
+			c.labels, err = ClusterQR(fac.SmallKEigenVectors(k))
+		}
+		if err != nil {
+			return err
+		}
+	default:
+		reduced = fac.SmallKEigenVectors(c.ReducedDim)
+		if c.RowNormalize {
+			reduced = matrix.DenseNormalizeRows(mat.DenseCopyOf(reduced))
+		}
+		src := c.KMeans.RandomState
+		if c.RandomState != nil {
+			src = c.RandomState
+		}
+		if err = c.KMeans.checkParams(reduced); err != nil {
+			return err
+		}
+		if err = c.KMeans.partialFit(ctx, reduced, src); err != nil {
+			return err
+		}
+		c.labels = c.KMeans.Labels()
 	}
 	c.done = true
 	return nil
 }
 
-func (c *SpecClustering) check() {
-	n := c.Similarities.Symmetric()
+func (c *SpecClustering) check(sim mat.Symmetric) error {
+	n := sim.Symmetric()
+	if n == 0 {
+		return ErrEmptyInput
+	}
 
 	if c.NClusters > n || c.NClusters <= 1 {
-		panic(ErrInvalidArgument)
+		return &ParamError{Field: "NClusters", Value: c.NClusters}
 	}
 
 	// 1< abs(c.ReducedDim) <=n
 	if c.ReducedDim < -n || (-1 <= c.ReducedDim && c.ReducedDim <= 1) ||
 		c.ReducedDim > n {
-		panic(ErrInvalidArgument)
+		return &ParamError{Field: "ReducedDim", Value: c.ReducedDim}
+	}
+	switch c.AssignLabels {
+	case KMeansAssign:
+		if c.KMeans == nil {
+			return &ParamError{Field: "KMeans", Value: nil}
+		}
+	case DiscretizeAssign, ClusterQRAssign:
+		if c.NClusters > utils.If(c.ReducedDim > 0, c.ReducedDim, -c.ReducedDim).(int) {
+			return &ParamError{Field: "ReducedDim", Value: c.ReducedDim}
+		}
+	default:
+		return &ParamError{Field: "AssignLabels", Value: c.AssignLabels}
 	}
+	if c.CutType != RatioCut && c.CutType != NCut {
+		return &ParamError{Field: "CutType", Value: c.CutType}
+	}
+	switch c.laplacian() {
+	case UnnormalizedLaplacian, SymmetricLaplacian, RandomWalkLaplacian:
+	default:
+		return &ParamError{Field: "Laplacian", Value: c.Laplacian}
+	}
+	return nil
+}
+
+// laplacian 实际使用的拉普拉斯矩阵类型
+func (c *SpecClustering) laplacian() string {
+	if c.Laplacian != "" {
+		return c.Laplacian
+	}
+	return utils.If(c.CutType == NCut, SymmetricLaplacian, UnnormalizedLaplacian).(string)
 }
 
 func (c *SpecClustering) HasFitted() bool { return c.done }
 
+func (c *SpecClustering) FitPredict(X mat.Matrix) ([]int, error) {
+	if err := c.Fit(X); err != nil {
+		return nil, err
+	}
+	return c.Labels(), nil
+}
+
+// Predict X的每一行为新样本与训练样本之间的相似度，新样本归入平均相似度最大的类
+func (c *SpecClustering) Predict(X mat.Matrix) []int {
+	return predictBySimilarity(X, c.Labels(), c.NClusters)
+}
+
+func (c *SpecClustering) NumClusters() int { return c.NClusters }
+
+// Centers 谱嵌入空间中的类中心，只有AssignLabels为"kmeans"时可用
 func (c *SpecClustering) Centers(i int) mat.Vector {
 	utils.Assert(c.HasFitted(), ErrFitHasNotDone)
+	utils.Assert(c.AssignLabels == KMeansAssign, &ParamError{Field: "AssignLabels", Value: c.AssignLabels})
 	return c.KMeans.Center(i)
 }
 
 func (c *SpecClustering) Labels() []int {
 	utils.Assert(c.HasFitted(), ErrFitHasNotDone)
-	return c.KMeans.Labels()
+	return c.labels
 }
@@ -0,0 +1,120 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/24 21:05
+ */
+
+package cluster
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/yinyajun/golearn/cluster/metrics"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestSpecClustering_Laplacian(t *testing.T) {
+	sim, truth := cliques(3, 10, 0.1)
+
+	for _, laplacian := range []string{"", UnnormalizedLaplacian, SymmetricLaplacian, RandomWalkLaplacian} {
+		for _, rowNormalize := range []bool{false, true} {
+			c := NewSpectralClustering(sim, 3)
+			c.ReducedDim, c.CutType = 3, NCut
+			c.Laplacian, c.RowNormalize = laplacian, rowNormalize
+			c.RandomState = rand.NewSource(1)
+			labels, err := c.FitPredict(sim)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if score, _ := metrics.AdjustedRandIndex(truth, labels); score != 1 {
+				t.Fatalf("laplacian=%q rowNormalize=%v: unexpected labels %v", laplacian, rowNormalize, labels)
+			}
+			if c.KMeans.RandomState != nil {
+				t.Fatalf("RandomState should not be written into KMeans")
+			}
+		}
+	}
+
+	c := NewSpectralClustering(sim, 3)
+	c.Laplacian = "rw"
+	if err := c.Fit(sim); err == nil {
+		t.Errorf("expected error for unknown laplacian")
+	}
+}
+
+func TestSpecClustering_AssignLabels(t *testing.T) {
+	sim, truth := cliques(4, 8, 0.1)
+
+	for _, assign := range []string{DiscretizeAssign, ClusterQRAssign} {
+		for _, laplacian := range []string{UnnormalizedLaplacian, SymmetricLaplacian} {
+			var first []int
+			for run := 0; run < 2; run++ {
+				c := NewSpectralClustering(sim, 4)
+				c.AssignLabels, c.Laplacian = assign, laplacian
+				labels, err := c.FitPredict(sim)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if score, _ := metrics.AdjustedRandIndex(truth, labels); score != 1 {
+					t.Fatalf("%s/%s: unexpected labels %v", assign, laplacian, labels)
+				}
+				if first == nil {
+					first = labels
+				} else if !reflect.DeepEqual(first, labels) {
+					t.Fatalf("%s/%s: labels changed between runs: %v vs %v", assign, laplacian, first, labels)
+				}
+			}
+		}
+	}
+
+	c := NewSpectralClustering(sim, 4)
+	c.AssignLabels, c.ReducedDim = DiscretizeAssign, 3
+	if err := c.Fit(sim); err == nil {
+		t.Errorf("expected error for ReducedDim < NClusters")
+	}
+	c.AssignLabels = "agglomerative"
+	if err := c.Fit(sim); err == nil {
+		t.Errorf("expected error for unknown AssignLabels")
+	}
+}
+
+func TestDiscretize(t *testing.T) {
+	// 指示矩阵（列归一化）经过一个旋转与少量扰动后的嵌入
+	var (
+		truth  = []int{0, 0, 0, 1, 1, 2, 2, 2, 2}
+		n, k   = len(truth), 3
+		theta  = 0.7
+		rotate = mat.NewDense(k, k, []float64{
+			math.Cos(theta), -math.Sin(theta), 0,
+			math.Sin(theta), math.Cos(theta), 0,
+			0, 0, 1,
+		})
+		rng       = rand.New(rand.NewSource(1))
+		indicator = mat.NewDense(n, k, nil)
+		vectors   mat.Dense
+	)
+	for i, g := range truth {
+		indicator.Set(i, g, 1)
+	}
+	for j := 0; j < k; j++ {
+		col := indicator.ColView(j).(*mat.VecDense)
+		col.ScaleVec(1/mat.Norm(col, 2), col)
+	}
+	vectors.Mul(indicator, rotate)
+	vectors.Apply(func(i, j int, v float64) float64 { return v + 0.01*rng.NormFloat64() }, &vectors)
+
+	for name, assign := range map[string]func(mat.Matrix) ([]int, error){"discretize": Discretize, "cluster_qr": ClusterQR} {
+		labels, err := assign(&vectors)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if score, _ := metrics.AdjustedRandIndex(truth, labels); score != 1 {
+			t.Errorf("%s: unexpected labels %v", name, labels)
+		}
+		if _, err := assign(&mat.Dense{}); err == nil {
+			t.Errorf("%s: expected error for empty input", name)
+		}
+	}
+}
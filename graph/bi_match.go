@@ -4,3 +4,177 @@
  */
 
 package graph
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Matching 二分图匹配的结果
+type Matching struct {
+	XToY   []int   // X[i]匹配的Y中的位置（未匹配为-1）
+	YToX   []int   // Y[j]匹配的X中的位置（未匹配为-1）
+	Size   int     // 匹配的边数
+	Weight float64 // 匹配边的权重之和
+}
+
+// MaxWeightMatch 最大权匹配（KM算法）
+func (g *BiGraph) MaxWeightMatch() (*Matching, error) {
+	w := g.WeightMatrix()
+	if w == nil {
+		return nil, ErrEmptyInput
+	}
+	return KuhnMunkres(w, true)
+}
+
+// MinWeightMatch 最小权匹配（KM算法）
+func (g *BiGraph) MinWeightMatch() (*Matching, error) {
+	w := g.WeightMatrix()
+	if w == nil {
+		return nil, ErrEmptyInput
+	}
+	return KuhnMunkres(w, false)
+}
+
+// KuhnMunkres 带权二分图的最优匹配（Kuhn-Munkres / Hungarian算法，带顶点势的最短增广路实现，O(n²m)）
+// weights为|X|×|Y|的边权重矩阵，可以不是方阵；NaN表示没有边，不参与匹配。
+// 匹配数首先最大（没有缺失边时为min(|X|,|Y|)），在所有最大匹配中取权重之和最大（maximize）或最小的一个
+func KuhnMunkres(weights mat.Matrix, maximize bool) (*Matching, error) {
+	r, c := weights.Dims()
+	if r == 0 || c == 0 {
+		return nil, ErrEmptyInput
+	}
+	// 转置使得行数n <= 列数m，每一行都会被分配到某一列
+	var (
+		transposed = r > c
+		n, m       = r, c
+		sign       = 1.0
+		maxAbs     float64
+	)
+	if transposed {
+		n, m = c, r
+	}
+	if maximize {
+		sign = -1
+	}
+	at := func(i, j int) float64 {
+		if transposed {
+			return weights.At(j, i)
+		}
+		return weights.At(i, j)
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < m; j++ {
+			w := at(i, j)
+			if math.IsInf(w, 0) {
+				return nil, &ParamError{Field: "weights", Value: fmt.Sprintf("(%d,%d)=%v", i, j, w)}
+			}
+			if !math.IsNaN(w) {
+				maxAbs = math.Max(maxAbs, math.Abs(w))
+			}
+		}
+	}
+	// 代价缩放到[-1,1]（避免权重很大时溢出），缺失边的代价为missing：
+	// 任意两个分配的代价之差（不计缺失边）不超过2n，所以最优分配首先使用最少的缺失边，即匹配数最大
+	var (
+		missing = 2*float64(n) + 1
+		cost    = mat.NewDense(n, m, nil)
+	)
+	if maxAbs > 0 {
+		sign /= maxAbs
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < m; j++ {
+			if w := at(i, j); math.IsNaN(w) {
+				cost.Set(i, j, missing)
+			} else {
+				cost.Set(i, j, sign*w)
+			}
+		}
+	}
+
+	rowOf := hungarian(cost)
+	res := &Matching{XToY: make([]int, r), YToX: make([]int, c)}
+	for i := range res.XToY {
+		res.XToY[i] = -1
+	}
+	for j := range res.YToX {
+		res.YToX[j] = -1
+	}
+	for j, i := range rowOf {
+		if i < 0 || math.IsNaN(at(i, j)) {
+			continue
+		}
+		x, y := i, j
+		if transposed {
+			x, y = j, i
+		}
+		res.XToY[x], res.YToX[y] = y, x
+		res.Size++
+		res.Weight += weights.At(x, y)
+	}
+	return res, nil
+}
+
+// hungarian n×m（n <= m）代价矩阵的最小代价分配，返回每一列分配到的行（未分配为-1）
+// 逐行加入，用Dijkstra式的最短增广路维护对偶变量u（行）、v（列），始终满足 u_i + v_j <= cost_ij
+func hungarian(cost *mat.Dense) []int {
+	var (
+		n, m = cost.Dims()
+		u    = make([]float64, n+1)
+		v    = make([]float64, m+1)
+		p    = make([]int, m+1) // p[j]为第j列匹配的行（从1开始编号，0表示未匹配；第0列为虚拟列）
+		way  = make([]int, m+1) // 增广路上第j列的前驱列
+		minv = make([]float64, m+1)
+		used = make([]bool, m+1)
+	)
+	for i := 1; i <= n; i++ {
+		p[0] = i
+		j0 := 0
+		for j := range minv {
+			minv[j], used[j] = math.Inf(1), false
+		}
+		for p[j0] != 0 {
+			used[j0] = true
+			var (
+				i0    = p[j0]
+				delta = math.Inf(1)
+				j1    int
+			)
+			for j := 1; j <= m; j++ {
+				if used[j] {
+					continue
+				}
+				if cur := cost.At(i0-1, j-1) - u[i0] - v[j]; cur < minv[j] {
+					minv[j], way[j] = cur, j0
+				}
+				if minv[j] < delta {
+					delta, j1 = minv[j], j
+				}
+			}
+			for j := 0; j <= m; j++ {
+				if used[j] {
+					u[p[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			j0 = j1
+		}
+		// 沿增广路翻转匹配
+		for j0 != 0 {
+			j1 := way[j0]
+			p[j0] = p[j1]
+			j0 = j1
+		}
+	}
+
+	rowOf := make([]int, m)
+	for j := 1; j <= m; j++ {
+		rowOf[j-1] = p[j] - 1
+	}
+	return rowOf
+}
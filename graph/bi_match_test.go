@@ -0,0 +1,134 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/27 21:30
+ */
+
+package graph
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// bruteForceMatch 枚举所有匹配，返回最大匹配数以及该匹配数下的最优权重
+func bruteForceMatch(w *mat.Dense, maximize bool) (int, float64) {
+	var (
+		r, c      = w.Dims()
+		used      = make([]bool, c)
+		bestSize  = -1
+		bestValue float64
+		search    func(i, size int, value float64)
+	)
+	search = func(i, size int, value float64) {
+		if i == r {
+			better := value > bestValue
+			if !maximize {
+				better = value < bestValue
+			}
+			if size > bestSize || (size == bestSize && better) {
+				bestSize, bestValue = size, value
+			}
+			return
+		}
+		search(i+1, size, value) // X[i]不匹配
+		for j := 0; j < c; j++ {
+			if !used[j] && !math.IsNaN(w.At(i, j)) {
+				used[j] = true
+				search(i+1, size+1, value+w.At(i, j))
+				used[j] = false
+			}
+		}
+	}
+	search(0, 0, 0)
+	return bestSize, bestValue
+}
+
+func TestKuhnMunkres(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 200; trial++ {
+		var (
+			r, c = 1 + rng.Intn(5), 1 + rng.Intn(5)
+			w    = mat.NewDense(r, c, nil)
+		)
+		for i := 0; i < r; i++ {
+			for j := 0; j < c; j++ {
+				if rng.Float64() < 0.3 {
+					w.Set(i, j, math.NaN())
+				} else {
+					w.Set(i, j, float64(rng.Intn(21)-10))
+				}
+			}
+		}
+		for _, maximize := range []bool{true, false} {
+			res, err := KuhnMunkres(w, maximize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			size, value := bruteForceMatch(w, maximize)
+			if res.Size != size || math.Abs(res.Weight-value) > 1e-9 {
+				t.Fatalf("maximize=%v %v: expected size %d weight %v, got %+v",
+					maximize, mat.Formatted(w), size, value, res)
+			}
+			var sum float64
+			for i, j := range res.XToY {
+				if j >= 0 {
+					if res.YToX[j] != i || math.IsNaN(w.At(i, j)) {
+						t.Fatalf("inconsistent matching %+v", res)
+					}
+					sum += w.At(i, j)
+				}
+			}
+			if sum != res.Weight {
+				t.Fatalf("weight %v does not match the edges %v", res.Weight, sum)
+			}
+		}
+	}
+}
+
+func TestKuhnMunkres_LargeWeights(t *testing.T) {
+	// 权重很大时仍然首先使匹配数最大：x1-y0的权重最大，但完美匹配只能是x0-y0与x1-y1
+	w := mat.NewDense(2, 2, []float64{
+		1e308, math.NaN(),
+		1e308, -1e308,
+	})
+	res, err := KuhnMunkres(w, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Size != 2 || res.XToY[0] != 0 || res.XToY[1] != 1 || res.Weight != 0 {
+		t.Errorf("unexpected matching %+v", res)
+	}
+}
+
+func TestBiGraph_MaxWeightMatch(t *testing.T) {
+	g := NewBiGraph([]Vertex{"a", "b", "c", "x", "y"}, []int{0, 1, 2}, []int{3, 4})
+	g.AddEdge(0, 0, 3)
+	g.AddEdge(0, 1, 5)
+	g.AddEdge(1, 1, 4)
+	g.AddEdge(2, 0, 1)
+	if !g.HasEdge(1, 1) || g.HasEdge(1, 0) {
+		t.Fatalf("unexpected edges %v", mat.Formatted(g.WeightMatrix()))
+	}
+
+	res, err := g.MaxWeightMatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Size != 2 || res.Weight != 7 || res.XToY[0] != 0 || res.XToY[1] != 1 || res.XToY[2] != -1 {
+		t.Errorf("unexpected max matching %+v", res)
+	}
+	if res, _ = g.MinWeightMatch(); res.Size != 2 || res.Weight != 5 || res.XToY[2] != 0 || res.XToY[1] != 1 {
+		t.Errorf("unexpected min matching %+v", res)
+	}
+
+	if _, err = NewBiGraph(nil, nil, []int{0}).MaxWeightMatch(); err != ErrEmptyInput {
+		t.Errorf("expected ErrEmptyInput, got %v", err)
+	}
+	g.AddEdge(1, 0, math.Inf(1))
+	if _, err = g.MaxWeightMatch(); err == nil {
+		t.Errorf("expected error for infinite weight")
+	}
+}
@@ -0,0 +1,16 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/27 20:05
+ */
+
+package graph
+
+import "github.com/yinyajun/golearn/utils"
+
+var (
+	ErrInvalidArgument = utils.ErrInvalidArgument
+	ErrEmptyInput      = utils.ErrEmptyInput
+)
+
+// ParamError 参数不合法
+type ParamError = utils.ParamError
@@ -0,0 +1,287 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/30 20:25
+ */
+
+package graph
+
+import (
+	"errors"
+	"math"
+
+	"github.com/yinyajun/golearn/utils"
+)
+
+var (
+	ErrNegativeCycle = errors.New("negative cost cycle")
+	ErrNumerical     = errors.New("numerical failure")
+)
+
+// flowArc 残量网络中的弧，正向弧与反向弧成对存放（下标为2k与2k+1）
+type flowArc struct {
+	to   int
+	cap  int // 剩余容量
+	cost float64
+}
+
+// FlowNetwork 有向流网络，每条弧有整数容量与（可以为负的）浮点费用
+type FlowNetwork struct {
+	arcs []flowArc
+	adj  [][]int // adj[u]为从u出发的弧（包括反向弧）的下标
+}
+
+// NewFlowNetwork n个顶点、没有弧的流网络
+func NewFlowNetwork(n int) *FlowNetwork {
+	return &FlowNetwork{adj: make([][]int, n)}
+}
+
+// NewFlowNetworkFromGraph 由图构造流网络：每条边（无向图的每个方向）为一条弧，费用为边的权重，容量为capacity(e)
+func NewFlowNetworkFromGraph(g *Graph, capacity func(e Edge) int) *FlowNetwork {
+	f := NewFlowNetwork(g.NumVertices())
+	for i := range g.adj {
+		for _, e := range g.adj[i] {
+			if e.From != e.To {
+				f.AddArc(e.From, e.To, capacity(e), e.Weight)
+			}
+		}
+	}
+	return f
+}
+
+func (f *FlowNetwork) NumVertices() int { return len(f.adj) }
+
+// AddArc 添加from到to容量为capacity、单位费用为cost的弧，返回弧的编号（用于Flow查询）
+// 容量为负或者费用不是有限值的弧由MinCostFlow返回ParamError
+func (f *FlowNetwork) AddArc(from, to, capacity int, cost float64) int {
+	id := len(f.arcs)
+	f.arcs = append(f.arcs, flowArc{to: to, cap: capacity, cost: cost}, flowArc{to: from, cost: -cost})
+	f.adj[from] = append(f.adj[from], id)
+	f.adj[to] = append(f.adj[to], id+1)
+	return id
+}
+
+// Flow 编号为id的弧上当前的流量
+func (f *FlowNetwork) Flow(id int) int { return f.arcs[id^1].cap }
+
+// MinCostMaxFlow 从s到t的最小费用最大流
+func (f *FlowNetwork) MinCostMaxFlow(s, t int) (int, float64, error) {
+	return f.MinCostFlow(s, t, int(^uint(0)>>1))
+}
+
+// MinCostFlow 从s到t的流量不超过limit的最小费用流（连续最短路，Edmonds & Karp, 1972），返回流量与费用
+// 每一轮用Dijkstra按约化费用 c(u,v) + π(u) - π(v) >= 0 求最短路并更新势π，
+// 再在约化费用为0的弧上（按BFS分层）求阻塞流，一次增广所有最短路。
+// 有负费用的弧时先用Bellman-Ford求初始的势，存在从s可达的负费用环时返回ErrNegativeCycle。
+// 舍入误差导致最短路上的弧都不可行、无法继续增广时返回ErrNumerical（以及此前已经求得的流量与费用）。
+// 可以多次调用，在已有的流上继续增广
+func (f *FlowNetwork) MinCostFlow(s, t, limit int) (flow int, cost float64, err error) {
+	n := f.NumVertices()
+	if s < 0 || s >= n {
+		return 0, 0, &ParamError{Field: "s", Value: s}
+	}
+	if t < 0 || t >= n || t == s {
+		return 0, 0, &ParamError{Field: "t", Value: t}
+	}
+	if limit < 0 {
+		return 0, 0, &ParamError{Field: "limit", Value: limit}
+	}
+	if err = f.checkArcs(); err != nil {
+		return 0, 0, err
+	}
+	pot, err := f.potentials(s)
+	if err != nil {
+		return 0, 0, err
+	}
+	var (
+		eps   = 1e-9 * (1 + f.maxAbsCost())
+		level = make([]int, n)
+		next  = make([]int, n)
+	)
+	for flow < limit {
+		dist := f.dijkstra(s, pot)
+		if math.IsInf(dist[t], 1) {
+			break
+		}
+		// 不可达的顶点加上最大的有限距离，保持所有残量弧的约化费用非负
+		var maxDist float64
+		for _, d := range dist {
+			if !math.IsInf(d, 1) {
+				maxDist = math.Max(maxDist, d)
+			}
+		}
+		for v, d := range dist {
+			pot[v] += utils.If(math.IsInf(d, 1), maxDist, d).(float64)
+		}
+
+		admissible := func(u, id int) bool {
+			a := f.arcs[id]
+			return a.cap > 0 && a.cost+pot[u]-pot[a.to] <= eps
+		}
+		progressed := false
+		for flow < limit && f.levels(s, t, level, admissible) {
+			for v := range next {
+				next[v] = 0
+			}
+			pushed, c := f.block(s, t, limit-flow, level, next, admissible)
+			if pushed == 0 {
+				break
+			}
+			flow, cost, progressed = flow+pushed, cost+c, true
+		}
+		if !progressed { // t可达但沿最短路无法增广，只可能是舍入误差使势失效
+			return flow, cost, ErrNumerical
+		}
+	}
+	return flow, cost, nil
+}
+
+// potentials 初始的势：没有负费用时全为0，否则为从s出发的最短距离（队列优化的Bellman-Ford）
+func (f *FlowNetwork) potentials(s int) ([]float64, error) {
+	var (
+		n        = f.NumVertices()
+		pot      = make([]float64, n)
+		negative bool
+	)
+	for _, a := range f.arcs { // 包括已有流量的反向弧
+		negative = negative || (a.cap > 0 && a.cost < 0)
+	}
+	if !negative {
+		return pot, nil
+	}
+
+	var (
+		dist    = make([]float64, n)
+		inQueue = make([]bool, n)
+		count   = make([]int, n) // 顶点出队的次数，超过n说明有负费用环
+		queue   = []int{s}
+	)
+	for v := range dist {
+		dist[v] = math.Inf(1)
+	}
+	dist[s], inQueue[s] = 0, true
+	for len(queue) > 0 {
+		u := queue[0]
+		queue = queue[1:]
+		inQueue[u] = false
+		if count[u]++; count[u] > n {
+			return nil, ErrNegativeCycle
+		}
+		for _, id := range f.adj[u] {
+			a := f.arcs[id]
+			if a.cap > 0 && dist[u]+a.cost < dist[a.to] {
+				dist[a.to] = dist[u] + a.cost
+				if !inQueue[a.to] {
+					inQueue[a.to] = true
+					queue = append(queue, a.to)
+				}
+			}
+		}
+	}
+	for v, d := range dist {
+		if !math.IsInf(d, 1) { // 从s不可达的顶点以后也不会被访问
+			pot[v] = d
+		}
+	}
+	return pot, nil
+}
+
+// dijkstra 按约化费用求从s出发的最短距离（不可达为+Inf），约化费用因舍入误差为负时视为0
+func (f *FlowNetwork) dijkstra(s int, pot []float64) []float64 {
+	var (
+		n    = f.NumVertices()
+		dist = make([]float64, n)
+		done = make([]bool, n)
+		heap = utils.NewIndexMaxHeap(n) // key为-dist
+	)
+	for v := range dist {
+		dist[v] = math.Inf(1)
+	}
+	dist[s] = 0
+	heap.Push(s, 0)
+	for heap.Len() > 0 {
+		u, _ := heap.Max()
+		heap.Remove(u)
+		done[u] = true
+		for _, id := range f.adj[u] {
+			a := f.arcs[id]
+			if a.cap == 0 || done[a.to] {
+				continue
+			}
+			if d := dist[u] + math.Max(a.cost+pot[u]-pot[a.to], 0); d < dist[a.to] {
+				dist[a.to] = d
+				heap.Push(a.to, -d)
+			}
+		}
+	}
+	return dist
+}
+
+// levels 在可行弧上从s出发BFS分层，返回t是否可达
+func (f *FlowNetwork) levels(s, t int, level []int, admissible func(u, id int) bool) bool {
+	for v := range level {
+		level[v] = -1
+	}
+	level[s] = 0
+	queue := []int{s}
+	for len(queue) > 0 {
+		u := queue[0]
+		queue = queue[1:]
+		for _, id := range f.adj[u] {
+			if v := f.arcs[id].to; level[v] < 0 && admissible(u, id) {
+				level[v] = level[u] + 1
+				queue = append(queue, v)
+			}
+		}
+	}
+	return level[t] >= 0
+}
+
+// block 沿分层的可行弧DFS求流量不超过limit的阻塞流，返回流量与费用
+func (f *FlowNetwork) block(u, t, limit int, level, next []int, admissible func(u, id int) bool) (int, float64) {
+	if u == t {
+		return limit, 0
+	}
+	var (
+		pushed int
+		cost   float64
+	)
+	for ; next[u] < len(f.adj[u]) && pushed < limit; next[u]++ {
+		id := f.adj[u][next[u]]
+		v := f.arcs[id].to
+		if level[v] != level[u]+1 || !admissible(u, id) {
+			continue
+		}
+		d, c := f.block(v, t, utils.If(f.arcs[id].cap < limit-pushed, f.arcs[id].cap, limit-pushed).(int), level, next, admissible)
+		if d == 0 {
+			continue
+		}
+		f.arcs[id].cap -= d
+		f.arcs[id^1].cap += d
+		pushed, cost = pushed+d, cost+c+float64(d)*f.arcs[id].cost
+		if pushed == limit {
+			break // 这条弧可能还有剩余容量，下次从它继续
+		}
+	}
+	return pushed, cost
+}
+
+// checkArcs 检查每条正向弧的容量非负、费用为有限值
+func (f *FlowNetwork) checkArcs() error {
+	for id := 0; id < len(f.arcs); id += 2 {
+		a := f.arcs[id]
+		if a.cap < 0 {
+			return &ParamError{Field: "capacity", Value: a.cap}
+		}
+		if math.IsNaN(a.cost) || math.IsInf(a.cost, 0) {
+			return &ParamError{Field: "cost", Value: a.cost}
+		}
+	}
+	return nil
+}
+
+func (f *FlowNetwork) maxAbsCost() (m float64) {
+	for _, a := range f.arcs {
+		m = math.Max(m, math.Abs(a.cost))
+	}
+	return
+}
@@ -0,0 +1,99 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/30 22:05
+ */
+
+package graph
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestFlowNetwork_Assignment(t *testing.T) {
+	// 指派问题的最小费用流与KM的最小权匹配一致
+	rng := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 50; trial++ {
+		var (
+			r, c = 1 + rng.Intn(6), 1 + rng.Intn(6)
+			w    = mat.NewDense(r, c, nil)
+			f    = NewFlowNetwork(r + c + 2)
+			src  = r + c
+			sink = r + c + 1
+		)
+		for i := 0; i < r; i++ {
+			f.AddArc(src, i, 1, 0)
+			for j := 0; j < c; j++ {
+				w.Set(i, j, rng.Float64()*10-5)
+				f.AddArc(i, r+j, 1, w.At(i, j))
+			}
+		}
+		for j := 0; j < c; j++ {
+			f.AddArc(r+j, sink, 1, 0)
+		}
+		flow, cost, err := f.MinCostMaxFlow(src, sink)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := KuhnMunkres(w, false)
+		if flow != want.Size || math.Abs(cost-want.Weight) > 1e-9 {
+			t.Fatalf("expected flow %d cost %v, got %d %v", want.Size, want.Weight, flow, cost)
+		}
+	}
+}
+
+func TestFlowNetwork_MinCostFlow(t *testing.T) {
+	// s=0, t=3：两条路径 0-1-3（容量2，单位费用2）与 0-2-3（容量3，单位费用5），以及1->2（容量1，费用0）
+	f := NewFlowNetwork(4)
+	a := f.AddArc(0, 1, 3, 1)
+	f.AddArc(1, 3, 2, 1)
+	f.AddArc(0, 2, 3, 2)
+	f.AddArc(2, 3, 3, 3)
+	b := f.AddArc(1, 2, 1, 0)
+
+	flow, cost, err := f.MinCostFlow(0, 3, 2)
+	if err != nil || flow != 2 || cost != 4 || f.Flow(a) != 2 {
+		t.Fatalf("unexpected flow %d cost %v err %v", flow, cost, err)
+	}
+	// 在已有的流上继续增广：剩余的3个单位中1个经过0-1-2-3（费用4），2个经过0-2-3（费用5）
+	flow, cost, err = f.MinCostMaxFlow(0, 3)
+	if err != nil || flow != 3 || cost != 14 || f.Flow(a) != 3 || f.Flow(b) != 1 {
+		t.Fatalf("unexpected flow %d cost %v err %v", flow, cost, err)
+	}
+
+	if _, _, err = f.MinCostFlow(0, 0, 1); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument, got %v", err)
+	}
+	// 不合法的弧在求流时报错，而不是在AddArc时panic
+	f.AddArc(2, 3, -1, 0)
+	if _, _, err = f.MinCostMaxFlow(0, 3); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument for negative capacity, got %v", err)
+	}
+	f = NewFlowNetwork(2)
+	f.AddArc(0, 1, 1, math.NaN())
+	if _, _, err = f.MinCostMaxFlow(0, 1); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument for NaN cost, got %v", err)
+	}
+}
+
+func TestFlowNetwork_NegativeCost(t *testing.T) {
+	g := NewGraph(4, true)
+	g.AddEdge(0, 1, 1)
+	g.AddEdge(1, 2, -3)
+	g.AddEdge(0, 2, 1)
+	g.AddEdge(2, 3, 1)
+	f := NewFlowNetworkFromGraph(g, func(e Edge) int { return 1 })
+	if flow, cost, err := f.MinCostMaxFlow(0, 3); err != nil || flow != 1 || cost != -1 {
+		t.Fatalf("unexpected flow %d cost %v err %v", flow, cost, err)
+	}
+
+	g.AddEdge(2, 1, 1) // 1->2->1 为负费用环
+	f = NewFlowNetworkFromGraph(g, func(e Edge) int { return 1 })
+	if _, _, err := f.MinCostMaxFlow(0, 3); err != ErrNegativeCycle {
+		t.Errorf("expected ErrNegativeCycle, got %v", err)
+	}
+}
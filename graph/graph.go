@@ -5,13 +5,280 @@
 
 package graph
 
+import (
+	"math"
+
+	"github.com/yinyajun/golearn/matrix"
+	"github.com/yinyajun/golearn/utils"
+	"gonum.org/v1/gonum/mat"
+)
+
 type Vertex interface{}
 
+// Edge 带权边（无向图中From与To可以互换）
+type Edge struct {
+	From, To int
+	Weight   float64
+}
+
+// Graph 邻接表表示的带权图，顶点编号为0..n-1，Vertices[i]为顶点i携带的数据
+// 无向图的每条边在两个端点的邻接表中各存一次（自环只存一次）；两个顶点之间最多一条边
+type Graph struct {
+	Vertices []Vertex
+	Directed bool     // 是否为有向图
+	adj      [][]Edge // adj[i]为从i出发的边
+	inDegree []int    // 有向图的入度
+	nEdges   int
+}
+
+// NewGraph n个顶点、没有边的图
+func NewGraph(n int, directed bool) *Graph {
+	return &Graph{
+		Vertices: make([]Vertex, n),
+		Directed: directed,
+		adj:      make([][]Edge, n),
+		inDegree: make([]int, n),
+	}
+}
+
+// NewGraphFromSimilarity 由相似度矩阵构造无向图：i != j且w_ij > threshold时有一条权重为w_ij的边
+// sim可以是稠密的*mat.SymDense或稀疏的*matrix.SymCSR（只遍历非零元素）
+func NewGraphFromSimilarity(sim mat.Symmetric, threshold float64) *Graph {
+	n := sim.Symmetric()
+	g := NewGraph(n, false)
+	for i := 0; i < n; i++ {
+		matrix.DoRowNonZero(sim, i, func(j int, w float64) {
+			if j > i && w > threshold {
+				g.appendEdge(i, j, w)
+			}
+		})
+	}
+	return g
+}
+
+// NewGraphFromDistances 由距离矩阵（例如matrix.Distances.SelfCartesian的结果）构造无向图：
+// i != j且d_ij <= threshold时有一条权重为d_ij的边
+func NewGraphFromDistances(dist mat.Symmetric, threshold float64) *Graph {
+	n := dist.Symmetric()
+	g := NewGraph(n, false)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if d := dist.At(i, j); d <= threshold {
+				g.appendEdge(i, j, d)
+			}
+		}
+	}
+	return g
+}
+
+func (g *Graph) NumVertices() int { return len(g.adj) }
+
+// NumEdges 边数（无向边只计一次）
+func (g *Graph) NumEdges() int { return g.nEdges }
+
+// AddVertex 添加顶点，返回其编号
+func (g *Graph) AddVertex(v Vertex) int {
+	g.Vertices = append(g.Vertices, v)
+	g.adj = append(g.adj, nil)
+	g.inDegree = append(g.inDegree, 0)
+	return len(g.adj) - 1
+}
+
+// AddEdge 添加from到to的边，边已存在时覆盖其权重
+// 需要在from的邻接表中查找已有的边，代价为O(Degree(from))；批量构造稠密图时注意总代价为O(V·E)
+func (g *Graph) AddEdge(from, to int, w float64) {
+	if g.setWeight(from, to, w) {
+		if !g.Directed {
+			g.setWeight(to, from, w)
+		}
+		return
+	}
+	g.appendEdge(from, to, w)
+}
+
+// appendEdge 不检查边是否已存在，直接添加from到to的边（调用方保证边不重复）
+func (g *Graph) appendEdge(from, to int, w float64) {
+	g.adj[from] = append(g.adj[from], Edge{From: from, To: to, Weight: w})
+	if g.Directed {
+		g.inDegree[to]++
+	} else if from != to {
+		g.adj[to] = append(g.adj[to], Edge{From: to, To: from, Weight: w})
+	}
+	g.nEdges++
+}
+
+// RemoveEdge 删除from到to的边，返回边是否存在（与AddEdge相同，需要查找边，代价为O(Degree(from))）
+func (g *Graph) RemoveEdge(from, to int) bool {
+	if !g.removeEdge(from, to) {
+		return false
+	}
+	if g.Directed {
+		g.inDegree[to]--
+	} else if from != to {
+		g.removeEdge(to, from)
+	}
+	g.nEdges--
+	return true
+}
+
+// Weight from到to的边的权重，以及边是否存在
+func (g *Graph) Weight(from, to int) (float64, bool) {
+	if t := g.find(from, to); t >= 0 {
+		return g.adj[from][t].Weight, true
+	}
+	return 0, false
+}
+
+func (g *Graph) HasEdge(from, to int) bool { return g.find(from, to) >= 0 }
+
+// DoNeighbors 对从i出发的每条边调用fn(j, w)，顺序为边的添加顺序（删除边会改变顺序）
+func (g *Graph) DoNeighbors(i int, fn func(j int, w float64)) {
+	for _, e := range g.adj[i] {
+		fn(e.To, e.Weight)
+	}
+}
+
+// Edges 所有的边（无向边只返回From <= To的一次）
+func (g *Graph) Edges() []Edge {
+	res := make([]Edge, 0, g.nEdges)
+	for i := range g.adj {
+		for _, e := range g.adj[i] {
+			if g.Directed || e.From <= e.To {
+				res = append(res, e)
+			}
+		}
+	}
+	return res
+}
+
+// Degree 顶点i的度（有向图为出度；无向图的自环计一次）
+func (g *Graph) Degree(i int) int { return len(g.adj[i]) }
+
+// InDegree 顶点i的入度（无向图与Degree相同）
+func (g *Graph) InDegree(i int) int {
+	if g.Directed {
+		return g.inDegree[i]
+	}
+	return len(g.adj[i])
+}
+
+// WeightedDegree 从i出发的边的权重之和
+func (g *Graph) WeightedDegree(i int) (s float64) {
+	for _, e := range g.adj[i] {
+		s += e.Weight
+	}
+	return
+}
+
+func (g *Graph) find(from, to int) int {
+	for t, e := range g.adj[from] {
+		if e.To == to {
+			return t
+		}
+	}
+	return -1
+}
+
+func (g *Graph) setWeight(from, to int, w float64) bool {
+	t := g.find(from, to)
+	if t < 0 {
+		return false
+	}
+	g.adj[from][t].Weight = w
+	return true
+}
+
+// removeEdge 从from的邻接表中删除到to的边（与最后一条边交换后删除）
+func (g *Graph) removeEdge(from, to int) bool {
+	t := g.find(from, to)
+	if t < 0 {
+		return false
+	}
+	last := len(g.adj[from]) - 1
+	g.adj[from][t] = g.adj[from][last]
+	g.adj[from] = g.adj[from][:last]
+	return true
+}
+
+// BiGraph 带权二分图，X与Y为两侧顶点在Vertices中的下标
+// 边只在X与Y之间，方法中的x、y均为顶点在X、Y中的位置
 type BiGraph struct {
 	Vertices []Vertex
 	X, Y     []int
+	adj      *Graph // 无向图，X[x]编号为x，Y[y]编号为|X|+y
 }
 
-type Graph struct {
-	Vertices []Vertex
+// NewBiGraph 没有任何边的二分图
+func NewBiGraph(vertices []Vertex, X, Y []int) *BiGraph {
+	return &BiGraph{Vertices: vertices, X: X, Y: Y, adj: NewGraph(len(X)+len(Y), false)}
+}
+
+// NewBiGraphFromWeights 由|X|×|Y|的权重矩阵构造二分图，NaN表示没有边
+// 顶点为X = 0..|X|-1, Y = |X|..|X|+|Y|-1（Vertices的元素为nil）
+func NewBiGraphFromWeights(weights mat.Matrix) *BiGraph {
+	var (
+		r, c = weights.Dims()
+		g    = NewBiGraph(make([]Vertex, r+c), utils.Range(0, r, 1), utils.Range(r, r+c, 1))
+	)
+	for x := 0; x < r; x++ {
+		for y := 0; y < c; y++ {
+			if w := weights.At(x, y); !math.IsNaN(w) {
+				g.adj.appendEdge(x, r+y, w)
+			}
+		}
+	}
+	return g
+}
+
+func (g *BiGraph) NumEdges() int { return g.adj.NumEdges() }
+
+// AddEdge 添加（或覆盖）X[x]与Y[y]之间权重为w的边
+func (g *BiGraph) AddEdge(x, y int, w float64) { g.adj.AddEdge(g.checkX(x), g.checkY(y), w) }
+
+// RemoveEdge 删除X[x]与Y[y]之间的边，返回边是否存在
+func (g *BiGraph) RemoveEdge(x, y int) bool { return g.adj.RemoveEdge(g.checkX(x), g.checkY(y)) }
+
+// HasEdge X[x]与Y[y]之间是否有边
+func (g *BiGraph) HasEdge(x, y int) bool { return g.adj.HasEdge(g.checkX(x), g.checkY(y)) }
+
+// Weight X[x]与Y[y]之间的边的权重，以及边是否存在
+func (g *BiGraph) Weight(x, y int) (float64, bool) { return g.adj.Weight(g.checkX(x), g.checkY(y)) }
+
+// DoNeighborsX 对X[x]的每个邻居Y[y]调用fn(y, w)
+func (g *BiGraph) DoNeighborsX(x int, fn func(y int, w float64)) {
+	g.adj.DoNeighbors(g.checkX(x), func(j int, w float64) { fn(j-len(g.X), w) })
+}
+
+// DoNeighborsY 对Y[y]的每个邻居X[x]调用fn(x, w)
+func (g *BiGraph) DoNeighborsY(y int, fn func(x int, w float64)) {
+	g.adj.DoNeighbors(g.checkY(y), fn)
+}
+
+func (g *BiGraph) DegreeX(x int) int { return g.adj.Degree(g.checkX(x)) }
+
+func (g *BiGraph) DegreeY(y int) int { return g.adj.Degree(g.checkY(y)) }
+
+// checkX 检查x是X中的位置，返回其在内部无向图中的编号
+func (g *BiGraph) checkX(x int) int {
+	utils.Assert(x >= 0 && x < len(g.X), &ParamError{Field: "x", Value: x})
+	return x
+}
+
+// checkY 检查y是Y中的位置，返回其在内部无向图中的编号len(X)+y
+func (g *BiGraph) checkY(y int) int {
+	utils.Assert(y >= 0 && y < len(g.Y), &ParamError{Field: "y", Value: y})
+	return len(g.X) + y
+}
+
+// WeightMatrix |X|×|Y|的边权重矩阵，没有边的位置为NaN（|X|或|Y|为0时返回nil）
+func (g *BiGraph) WeightMatrix() *mat.Dense {
+	if len(g.X) == 0 || len(g.Y) == 0 {
+		return nil
+	}
+	res := mat.NewDense(len(g.X), len(g.Y), nil)
+	res.Apply(func(i, j int, v float64) float64 { return math.NaN() }, res)
+	for x := range g.X {
+		g.DoNeighborsX(x, func(y int, w float64) { res.Set(x, y, w) })
+	}
+	return res
 }
@@ -0,0 +1,116 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/28 20:50
+ */
+
+package graph
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/yinyajun/golearn/matrix"
+	"gonum.org/v1/gonum/mat"
+)
+
+func neighbors(g *Graph, i int) []int {
+	var res []int
+	g.DoNeighbors(i, func(j int, w float64) { res = append(res, j) })
+	sort.Ints(res)
+	return res
+}
+
+func TestGraph(t *testing.T) {
+	g := NewGraph(3, false)
+	g.AddEdge(0, 1, 2)
+	g.AddEdge(1, 2, 3)
+	g.AddEdge(2, 2, 1)
+	g.AddEdge(1, 0, 5) // 覆盖
+	if g.NumEdges() != 3 || g.Degree(1) != 2 || g.Degree(2) != 2 || g.InDegree(0) != 1 {
+		t.Fatalf("unexpected degrees %v", g.Edges())
+	}
+	if w, ok := g.Weight(0, 1); !ok || w != 5 || g.WeightedDegree(1) != 8 {
+		t.Fatalf("unexpected weight %v %v", w, ok)
+	}
+	if got := neighbors(g, 2); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("unexpected neighbors %v", got)
+	}
+	if !g.RemoveEdge(2, 1) || g.RemoveEdge(1, 2) || g.HasEdge(1, 2) || g.NumEdges() != 2 {
+		t.Fatalf("unexpected edges after removal %v", g.Edges())
+	}
+	if v := g.AddVertex("d"); v != 3 || g.NumVertices() != 4 || g.Vertices[3] != "d" {
+		t.Fatalf("unexpected vertex %d", v)
+	}
+
+	d := NewGraph(3, true)
+	d.AddEdge(0, 1, 1)
+	d.AddEdge(2, 1, 1)
+	d.AddEdge(1, 0, 1)
+	if d.NumEdges() != 3 || d.Degree(1) != 1 || d.InDegree(1) != 2 || d.HasEdge(1, 2) {
+		t.Fatalf("unexpected directed edges %v", d.Edges())
+	}
+	d.RemoveEdge(0, 1)
+	if d.InDegree(1) != 1 || !d.HasEdge(1, 0) || len(d.Edges()) != 2 {
+		t.Fatalf("unexpected directed edges after removal %v", d.Edges())
+	}
+}
+
+func TestNewGraphFromSimilarity(t *testing.T) {
+	sim := mat.NewSymDense(3, []float64{
+		1, 0.5, 0,
+		0.5, 1, 0.1,
+		0, 0.1, 1,
+	})
+	for _, sim := range []mat.Symmetric{sim, matrix.SymCSRFrom(sim)} {
+		g := NewGraphFromSimilarity(sim, 0.2)
+		if g.NumEdges() != 1 || !g.HasEdge(1, 0) || g.HasEdge(0, 0) {
+			t.Fatalf("unexpected edges %v", g.Edges())
+		}
+	}
+
+	dist := mat.NewSymDense(3, []float64{
+		0, 1, 3,
+		1, 0, 2,
+		3, 2, 0,
+	})
+	g := NewGraphFromDistances(dist, 2)
+	if w, _ := g.Weight(2, 1); g.NumEdges() != 2 || w != 2 || g.HasEdge(0, 2) {
+		t.Fatalf("unexpected edges %v", g.Edges())
+	}
+}
+
+func TestBiGraph(t *testing.T) {
+	w := mat.NewDense(2, 3, []float64{
+		1, math.NaN(), 2,
+		math.NaN(), 3, math.NaN(),
+	})
+	g := NewBiGraphFromWeights(w)
+	if g.NumEdges() != 3 || g.DegreeX(0) != 2 || g.DegreeY(1) != 1 || g.DegreeY(2) != 1 {
+		t.Fatalf("unexpected degrees")
+	}
+	var xs []int
+	g.DoNeighborsY(2, func(x int, w float64) { xs = append(xs, x) })
+	if len(xs) != 1 || xs[0] != 0 {
+		t.Fatalf("unexpected neighbors %v", xs)
+	}
+	if !g.RemoveEdge(0, 2) || g.HasEdge(0, 2) || g.DegreeY(2) != 0 {
+		t.Fatalf("unexpected edges after removal")
+	}
+	got := g.WeightMatrix()
+	w.Set(0, 2, math.NaN())
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 3; j++ {
+			if a, b := got.At(i, j), w.At(i, j); a != b && !(math.IsNaN(a) && math.IsNaN(b)) {
+				t.Fatalf("expected %v, got %v", mat.Formatted(w), mat.Formatted(got))
+			}
+		}
+	}
+	// y = -1在内部无向图中是X的最后一个顶点，必须被拒绝
+	defer func() {
+		if pe, ok := recover().(*ParamError); !ok || pe.Field != "y" {
+			t.Errorf("expected panic with ParamError on y")
+		}
+	}()
+	g.AddEdge(1, -1, 1)
+}
@@ -0,0 +1,169 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/29 21:10
+ */
+
+package graph
+
+import "math"
+
+// VertexCover 二分图的顶点覆盖（每条边至少有一个端点在覆盖中），X、Y为覆盖中的顶点在X、Y中的位置
+type VertexCover struct {
+	X, Y []int
+}
+
+// HopcroftKarp 最大基数匹配（Hopcroft & Karp, 1973），忽略边的权重，O(E·sqrt(V))
+// 每一轮从所有未匹配的X顶点出发BFS分层，再沿层DFS找出一组极大的不相交最短增广路。
+// 同时由König定理得到最小顶点覆盖（大小等于最大匹配数）；返回的Matching.Weight为匹配边的权重之和
+func (g *BiGraph) HopcroftKarp() (*Matching, *VertexCover) {
+	var (
+		nx, ny = len(g.X), len(g.Y)
+		adj    = g.adj.adj // X[x]的邻接表为adj[x]，边的To为nx+y
+		matchX = make([]int, nx)
+		matchY = make([]int, ny)
+		dist   = make([]int, nx)
+	)
+	for x := range matchX {
+		matchX[x] = -1
+	}
+	for y := range matchY {
+		matchY[y] = -1
+	}
+	for {
+		limit, ok := g.layer(adj, matchX, matchY, dist)
+		if !ok {
+			break
+		}
+		g.augment(adj, matchX, matchY, dist, limit)
+	}
+
+	res := &Matching{XToY: matchX, YToX: matchY}
+	for x, y := range matchX {
+		if y >= 0 {
+			w, _ := g.Weight(x, y)
+			res.Size++
+			res.Weight += w
+		}
+	}
+	return res, g.konig(adj, matchX, matchY)
+}
+
+// layer 从未匹配的X顶点出发沿交错路BFS，dist为X顶点的层数（不可达为MaxInt），
+// 返回最短增广路终点之前的X顶点所在的层limit以及是否存在增广路；层数超过limit的顶点不再扩展
+func (g *BiGraph) layer(adj [][]Edge, matchX, matchY, dist []int) (limit int, found bool) {
+	var (
+		nx    = len(g.X)
+		queue []int
+	)
+	limit = math.MaxInt32
+	for x := range dist {
+		if matchX[x] < 0 {
+			dist[x] = 0
+			queue = append(queue, x)
+		} else {
+			dist[x] = math.MaxInt32
+		}
+	}
+	for len(queue) > 0 {
+		u := queue[0]
+		queue = queue[1:]
+		if dist[u] > limit {
+			break // BFS按层出队，之后的顶点都在更深的层
+		}
+		for _, e := range adj[u] {
+			v := matchY[e.To-nx]
+			if v < 0 {
+				limit, found = dist[u], true
+			} else if dist[v] == math.MaxInt32 && dist[u] < limit {
+				dist[v] = dist[u] + 1
+				queue = append(queue, v)
+			}
+		}
+	}
+	return limit, found
+}
+
+// augment 沿BFS的分层（dist递增1）DFS寻找不相交的最短增广路并翻转（只在第limit层接受未匹配的Y顶点），
+// 用显式栈避免长路径时递归过深
+func (g *BiGraph) augment(adj [][]Edge, matchX, matchY, dist []int, limit int) {
+	var (
+		nx     = len(g.X)
+		next   = make([]int, nx) // 每个X顶点下一条待尝试的边
+		choice = make([]int, nx) // 栈中每个X顶点当前选择的Y顶点
+		stack  []int
+	)
+	for root := range matchX {
+		if matchX[root] >= 0 {
+			continue
+		}
+		stack = append(stack[:0], root)
+		for len(stack) > 0 {
+			u := stack[len(stack)-1]
+			if next[u] == len(adj[u]) { // u出发没有增广路，本轮不再访问
+				dist[u] = math.MaxInt32
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			y := adj[u][next[u]].To - nx
+			next[u]++
+			v := matchY[y]
+			if (v >= 0 && dist[v] != dist[u]+1) || (v < 0 && dist[u] != limit) {
+				continue
+			}
+			choice[u] = y
+			if v >= 0 {
+				stack = append(stack, v)
+				continue
+			}
+			// 找到增广路：栈中的每个X顶点改为匹配其选择的Y顶点
+			for _, x := range stack {
+				matchX[x], matchY[choice[x]] = choice[x], x
+			}
+			break
+		}
+	}
+}
+
+// konig 由最大匹配构造最小顶点覆盖：Z为从未匹配的X顶点出发沿交错路（X->Y非匹配边，Y->X匹配边）可达的顶点，
+// 覆盖为(X \ Z) ∪ (Y ∩ Z)
+func (g *BiGraph) konig(adj [][]Edge, matchX, matchY []int) *VertexCover {
+	var (
+		nx       = len(g.X)
+		visitedX = make([]bool, nx)
+		visitedY = make([]bool, len(g.Y))
+		queue    []int
+		cover    = &VertexCover{}
+	)
+	for x := range matchX {
+		if matchX[x] < 0 {
+			visitedX[x] = true
+			queue = append(queue, x)
+		}
+	}
+	for len(queue) > 0 {
+		u := queue[0]
+		queue = queue[1:]
+		for _, e := range adj[u] {
+			y := e.To - nx
+			if visitedY[y] || matchX[u] == y {
+				continue
+			}
+			visitedY[y] = true
+			if v := matchY[y]; v >= 0 && !visitedX[v] {
+				visitedX[v] = true
+				queue = append(queue, v)
+			}
+		}
+	}
+	for x, visited := range visitedX {
+		if !visited {
+			cover.X = append(cover.X, x)
+		}
+	}
+	for y, visited := range visitedY {
+		if visited {
+			cover.Y = append(cover.Y, y)
+		}
+	}
+	return cover
+}
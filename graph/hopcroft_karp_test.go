@@ -0,0 +1,118 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/29 22:30
+ */
+
+package graph
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// checkCover 检查cover覆盖了所有边且大小等于匹配数
+func checkCover(t *testing.T, g *BiGraph, res *Matching, cover *VertexCover) {
+	var (
+		inX = make([]bool, len(g.X))
+		inY = make([]bool, len(g.Y))
+	)
+	for _, x := range cover.X {
+		inX[x] = true
+	}
+	for _, y := range cover.Y {
+		inY[y] = true
+	}
+	if len(cover.X)+len(cover.Y) != res.Size {
+		t.Fatalf("cover size %d != matching size %d", len(cover.X)+len(cover.Y), res.Size)
+	}
+	for x := range g.X {
+		g.DoNeighborsX(x, func(y int, w float64) {
+			if !inX[x] && !inY[y] {
+				t.Fatalf("edge (%d,%d) is not covered", x, y)
+			}
+		})
+		if y := res.XToY[x]; y >= 0 && (res.YToX[y] != x || !g.HasEdge(x, y)) {
+			t.Fatalf("inconsistent matching at %d", x)
+		}
+	}
+}
+
+func TestBiGraph_HopcroftKarp(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 200; trial++ {
+		var (
+			r, c = 1 + rng.Intn(8), 1 + rng.Intn(8)
+			w    = mat.NewDense(r, c, nil)
+		)
+		for i := 0; i < r; i++ {
+			for j := 0; j < c; j++ {
+				w.Set(i, j, math.NaN())
+				if rng.Float64() < 0.3 {
+					w.Set(i, j, 1)
+				}
+			}
+		}
+		g := NewBiGraphFromWeights(w)
+		res, cover := g.HopcroftKarp()
+		// 所有边的权重为1时KM的匹配数与权重均为最大匹配数
+		want, _ := KuhnMunkres(w, true)
+		if res.Size != want.Size || res.Weight != float64(want.Size) {
+			t.Fatalf("%v: expected size %d, got %+v", mat.Formatted(w), want.Size, res)
+		}
+		checkCover(t, g, res, cover)
+	}
+}
+
+func TestBiGraph_HopcroftKarpShortest(t *testing.T) {
+	// x1已匹配y0；x0可以直接匹配y1（长度1），也可以经过y0-x1-y2（长度3），一轮中只应当沿最短增广路增广
+	w := mat.NewDense(2, 3, []float64{
+		1, 1, math.NaN(),
+		1, math.NaN(), 1,
+	})
+	var (
+		g      = NewBiGraphFromWeights(w)
+		matchX = []int{-1, 0}
+		matchY = []int{1, -1, -1}
+		dist   = make([]int, 2)
+	)
+	limit, ok := g.layer(g.adj.adj, matchX, matchY, dist)
+	if !ok || limit != 0 {
+		t.Fatalf("expected shortest augmenting path at layer 0, got %d %v", limit, ok)
+	}
+	g.augment(g.adj.adj, matchX, matchY, dist, limit)
+	if matchX[0] != 1 || matchX[1] != 0 {
+		t.Errorf("expected x0-y1 and x1-y0, got %v", matchX)
+	}
+}
+
+func TestBiGraph_HopcroftKarpLarge(t *testing.T) {
+	var (
+		rng = rand.New(rand.NewSource(2))
+		n   = 20000
+		X   = make([]int, n)
+		Y   = make([]int, n)
+	)
+	for i := range X {
+		X[i], Y[i] = i, n+i
+	}
+	g := NewBiGraph(nil, X, Y)
+	// 一个完美匹配加上随机边，并且有一条贯穿所有顶点的长交错路
+	perm := rng.Perm(n)
+	for x, y := range perm {
+		g.AddEdge(x, y, 1)
+		if x+1 < n {
+			g.AddEdge(x+1, y, 1)
+		}
+		for k := 0; k < 3; k++ {
+			g.AddEdge(x, rng.Intn(n), 1)
+		}
+	}
+	res, cover := g.HopcroftKarp()
+	if res.Size != n {
+		t.Fatalf("expected perfect matching, got size %d", res.Size)
+	}
+	checkCover(t, g, res, cover)
+}
@@ -0,0 +1,168 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/23 20:12
+ */
+
+package matrix
+
+import (
+	"context"
+	"math"
+	"runtime"
+
+	"github.com/yinyajun/golearn/utils"
+	"gonum.org/v1/gonum/mat"
+)
+
+// Affinity 由数据点（每行一个）构造谱方法所需的相似度矩阵
+// Metric为样本之间的距离度量（例如utils.Euclidean），相似度由距离转换得到；对角元素（自环）均为0
+type Affinity struct {
+	Metric      utils.Metric // 距离度量
+	NGoroutines int          // 计算并发度
+}
+
+func NewAffinity(metric utils.Metric) *Affinity {
+	return &Affinity{
+		Metric:      metric,
+		NGoroutines: utils.If(runtime.NumCPU() > 1, runtime.NumCPU()/2, 1).(int),
+	}
+}
+
+func (a *Affinity) check(points *mat.Dense) error {
+	if points.IsEmpty() {
+		return ErrEmptyInput
+	}
+	if a.Metric == nil {
+		return &ParamError{Field: "Metric", Value: nil}
+	}
+	if a.NGoroutines < 1 {
+		return &ParamError{Field: "NGoroutines", Value: a.NGoroutines}
+	}
+	return nil
+}
+
+// Gaussian 高斯核 w_ij = exp(-d_ij^2 / (2 sigma^2))
+func (a *Affinity) Gaussian(points *mat.Dense, sigma float64) (*mat.SymDense, error) {
+	if !(sigma > 0) {
+		return nil, &ParamError{Field: "sigma", Value: sigma}
+	}
+	res, err := a.distances(points)
+	if err != nil {
+		return nil, err
+	}
+	n := res.Symmetric()
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			d := res.At(i, j)
+			res.SetSym(i, j, math.Exp(-d*d/(2*sigma*sigma)))
+		}
+	}
+	return res, nil
+}
+
+// SelfTuning 局部尺度的高斯核 w_ij = exp(-d_ij^2 / (sigma_i sigma_j))（Zelnik-Manor & Perona, 2004）
+// sigma_i为样本i到其第k近邻的距离；sigma_i为0（重复样本）时对应的相似度取1（d_ij=0）或0
+func (a *Affinity) SelfTuning(points *mat.Dense, k int) (*mat.SymDense, error) {
+	res, err := a.distances(points)
+	if err != nil {
+		return nil, err
+	}
+	n := res.Symmetric()
+	if k < 1 || k >= n {
+		return nil, &ParamError{Field: "k", Value: k}
+	}
+
+	var (
+		sigma = make([]float64, n)
+		row   = make([]float64, n)
+	)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			row[j] = res.At(i, j)
+		}
+		for _, j := range utils.KSmallest(row, k+1) { // 包括自身（距离为0）
+			sigma[i] = math.Max(sigma[i], row[j])
+		}
+	}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			d, s := res.At(i, j), sigma[i]*sigma[j]
+			switch {
+			case d == 0:
+				res.SetSym(i, j, 1)
+			case s == 0:
+				res.SetSym(i, j, 0)
+			default:
+				res.SetSym(i, j, math.Exp(-d*d/s))
+			}
+		}
+	}
+	return res, nil
+}
+
+// Epsilon epsilon邻域图：d_ij <= eps时w_ij = 1，否则为0
+// 逐行计算，只保存非零元素，不构造n×n的稠密矩阵
+func (a *Affinity) Epsilon(points *mat.Dense, eps float64) (*SymCSR, error) {
+	if err := a.check(points); err != nil {
+		return nil, err
+	}
+	if !(eps > 0) {
+		return nil, &ParamError{Field: "eps", Value: eps}
+	}
+	var (
+		n, _       = points.Dims()
+		dist       = MetricDist(points, a.Metric)
+		neighbors  = make([][]int, n)
+		rows, cols []int
+		vals       []float64
+	)
+	utils.Parallel(n, a.NGoroutines, func(i int) {
+		for j := i + 1; j < n; j++ {
+			if dist(i, j) <= eps {
+				neighbors[i] = append(neighbors[i], j)
+			}
+		}
+	})
+	for i := range neighbors {
+		for _, j := range neighbors[i] {
+			rows, cols = append(rows, i), append(cols, j)
+			vals = append(vals, 1)
+		}
+	}
+	return NewSymCSR(n, rows, cols, vals), nil
+}
+
+// MutualKNN 互为k近邻图：i是j的k近邻且j是i的k近邻时w_ij = 1，否则为0
+// 由KNNFilter（AllKNN）直接生成稀疏矩阵，不构造n×n的稠密矩阵
+func (a *Affinity) MutualKNN(points *mat.Dense, k int) (*SymCSR, error) {
+	if err := a.check(points); err != nil {
+		return nil, err
+	}
+	var (
+		n, _ = points.Dims()
+		dist = MetricDist(points, a.Metric)
+		d    = &Distances{
+			// KNNFilter保留绝对值最大的k个，将距离单调地转换为(0,1]的相似度
+			Dist:        func(i, j int) float64 { return 1 / (1 + dist(i, j)) },
+			NGoroutines: a.NGoroutines,
+		}
+	)
+	res, err := (&KNNFilter{Typ: AllKNN, K: k}).SparseSelfCartesian(d, utils.Range(0, n, 1))
+	if err != nil {
+		return nil, err
+	}
+	for t := range res.data {
+		res.data[t] = 1
+	}
+	return res, nil
+}
+
+// distances 样本两两之间的距离
+func (a *Affinity) distances(points *mat.Dense) (*mat.SymDense, error) {
+	if err := a.check(points); err != nil {
+		return nil, err
+	}
+	n, _ := points.Dims()
+	d := &Distances{Dist: MetricDist(points, a.Metric), NGoroutines: a.NGoroutines}
+	return d.SelfCartesianContext(context.Background(), utils.Range(0, n, 1))
+}
@@ -0,0 +1,63 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/23 22:40
+ */
+
+package matrix
+
+import (
+	"math"
+	"testing"
+
+	"github.com/yinyajun/golearn/utils"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestAffinity(t *testing.T) {
+	// 一维点0, 1, 3, 10
+	points := mat.NewDense(4, 1, []float64{0, 1, 3, 10})
+	a := NewAffinity(utils.Euclidean)
+
+	g, err := a.Gaussian(points, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(g.At(0, 2)-math.Exp(-9.0/8)) > 1e-12 || g.At(1, 1) != 0 {
+		t.Errorf("unexpected gaussian affinity %v", mat.Formatted(g))
+	}
+
+	// 第1近邻的距离：sigma = 1, 1, 2, 7
+	s, err := a.SelfTuning(points, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(s.At(2, 3)-math.Exp(-49.0/14)) > 1e-12 || math.Abs(s.At(0, 1)-math.Exp(-1)) > 1e-12 {
+		t.Errorf("unexpected self-tuning affinity %v", mat.Formatted(s))
+	}
+
+	e, err := a.Epsilon(points, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !mat.Equal(e, mat.NewSymDense(4, []float64{
+		0, 1, 0, 0,
+		1, 0, 1, 0,
+		0, 1, 0, 0,
+		0, 0, 0, 0,
+	})) {
+		t.Errorf("unexpected epsilon graph %v", mat.Formatted(e))
+	}
+
+	// 1近邻：0<->1互为近邻，3的近邻是1，10的近邻是3
+	m, err := a.MutualKNN(points, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.NNZ() != 2 || m.At(0, 1) != 1 {
+		t.Errorf("unexpected mutual kNN graph %v", mat.Formatted(m))
+	}
+
+	if _, err = a.SelfTuning(points, 4); err == nil {
+		t.Errorf("expected error for k >= n")
+	}
+}
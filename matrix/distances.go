@@ -6,6 +6,7 @@
 package matrix
 
 import (
+	"context"
 	"fmt"
 	"github.com/yinyajun/golearn/utils"
 	"gonum.org/v1/gonum/mat"
@@ -19,6 +20,20 @@ const (
 
 type DistFunc func(i, j int) float64
 
+// MetricDist 以metric度量points第i行与第j行之间的距离
+func MetricDist(points *mat.Dense, metric utils.Metric) DistFunc {
+	return func(i, j int) float64 {
+		return metric(points.RowView(i), points.RowView(j))
+	}
+}
+
+// SymmetricDist 从预计算的距离（相似度）矩阵中取值
+func SymmetricDist(m mat.Symmetric) DistFunc {
+	return func(i, j int) float64 {
+		return m.At(i, j)
+	}
+}
+
 type Distances struct {
 	Dist        DistFunc
 	Filters     []DistFilter
@@ -26,62 +41,63 @@ type Distances struct {
 }
 
 // Cartesian returns {<x, y> | x in X && y in Y}
-func (d *Distances) Cartesian(X, Y []int) *mat.Dense {
-	var (
-		limit = make(chan int, d.NGoroutines)
-		res   = mat.NewDense(len(X), len(Y), nil)
-	)
-	// 计算距离矩阵
-	for i, x := range X {
+func (d *Distances) Cartesian(X, Y []int) (*mat.Dense, error) {
+	return d.CartesianContext(context.Background(), X, Y)
+}
+
+// CartesianContext 同Cartesian，逐行分块计算，ctx取消时等待已启动的goroutine退出后返回ctx.Err()
+func (d *Distances) CartesianContext(ctx context.Context, X, Y []int) (*mat.Dense, error) {
+	if d.NGoroutines < 1 {
+		return nil, &ParamError{Field: "NGoroutines", Value: d.NGoroutines}
+	}
+	res := mat.NewDense(len(X), len(Y), nil)
+	// 计算距离矩阵（每个goroutine计算一行）
+	err := utils.ParallelContext(ctx, len(X), d.NGoroutines, func(i int) {
 		for j, y := range Y {
-			limit <- 1
-			go func(i, j, x, y int) {
-				res.Set(i, j, d.Dist(x, y))
-				<-limit
-			}(i, j, x, y)
+			res.Set(i, j, d.Dist(X[i], y))
 		}
+	})
+	if err != nil {
+		return nil, err
 	}
-	for i := 0; i < d.NGoroutines; i++ { // 确保最后一批goroutine完成job
-		limit <- 1
-	}
-	close(limit)
 	for _, f := range d.Filters {
-		f.FilterDense(res)
+		if err := f.FilterDense(res); err != nil {
+			return nil, err
+		}
 	}
-	return res
+	return res, nil
 }
 
 // SelfCartesian returns {<x, y>| x, y in Index}
-func (d *Distances) SelfCartesian(Index []int) *mat.SymDense {
-	var (
-		limit = make(chan int, d.NGoroutines)
-		res   = mat.NewSymDense(len(Index), nil)
-	)
-	// 计算距离矩阵
-	for i, x := range Index {
-		for j, y := range Index {
-			if j <= i {
-				continue
-			}
-			limit <- 1
-			go func(i, j, x, y int) {
-				res.SetSym(i, j, d.Dist(x, y))
-				<-limit
-			}(i, j, x, y)
-		}
+func (d *Distances) SelfCartesian(Index []int) (*mat.SymDense, error) {
+	return d.SelfCartesianContext(context.Background(), Index)
+}
+
+// SelfCartesianContext 同SelfCartesian，逐行分块计算，ctx取消时等待已启动的goroutine退出后返回ctx.Err()
+func (d *Distances) SelfCartesianContext(ctx context.Context, Index []int) (*mat.SymDense, error) {
+	if d.NGoroutines < 1 {
+		return nil, &ParamError{Field: "NGoroutines", Value: d.NGoroutines}
 	}
-	for i := 0; i < d.NGoroutines; i++ { // 确保最后一批goroutine完成job
-		limit <- 1
+	res := mat.NewSymDense(len(Index), nil)
+	// 计算距离矩阵（每个goroutine计算上三角的一行）
+	err := utils.ParallelContext(ctx, len(Index), d.NGoroutines, func(i int) {
+		for j := i + 1; j < len(Index); j++ {
+			res.SetSym(i, j, d.Dist(Index[i], Index[j]))
+		}
+	})
+	if err != nil {
+		return nil, err
 	}
-	close(limit)
 	for _, f := range d.Filters {
-		f.FilterSymmetric(res)
+		if err := f.FilterSymmetric(res); err != nil {
+			return nil, err
+		}
 	}
-	return res
+	return res, nil
 }
 
 // SubCartesian 从sim中提取子矩阵，特别注意s1,s2是sim的行列index的index
-func SubCartesian(sim *mat.SymDense, s1, s2 []int) *mat.Dense {
+func SubCartesian(sim mat.Symmetric, s1, s2 []int) *mat.Dense {
 	var (
 		res = mat.NewDense(len(s1), len(s2), nil)
 	)
@@ -93,9 +109,26 @@ func SubCartesian(sim *mat.SymDense, s1, s2 []int) *mat.Dense {
 	return res
 }
 
+// SubSymmetric 提取sim中行列均为index的对称子矩阵，稀疏矩阵的子矩阵仍是稀疏的
+func SubSymmetric(sim mat.Symmetric, index []int) mat.Symmetric {
+	if sp, ok := sim.(*SymCSR); ok {
+		return sp.Sub(index)
+	}
+	var (
+		sub = SubCartesian(sim, index, index)
+		res = mat.NewSymDense(len(index), nil)
+	)
+	for i := range index {
+		for j := i; j < len(index); j++ {
+			res.SetSym(i, j, sub.At(i, j))
+		}
+	}
+	return res
+}
+
 type DistFilter interface {
-	FilterSymmetric(*mat.SymDense)
-	FilterDense(dense *mat.Dense)
+	FilterSymmetric(*mat.SymDense) error
+	FilterDense(dense *mat.Dense) error
 }
 
 func showMatrix(s mat.Matrix) {
@@ -116,9 +149,12 @@ type KNNFilter struct {
 	K int
 }
 
-func (f *KNNFilter) FilterSymmetric(m *mat.SymDense) {
+func (f *KNNFilter) FilterSymmetric(m *mat.SymDense) error {
 	var rmMark func(int, int)
 
+	if f.K < 0 || f.K > m.Symmetric() {
+		return &ParamError{Field: "K", Value: f.K}
+	}
 	switch f.Typ {
 	case AllKNN:
 		rmMark = func(i, j int) {
@@ -135,7 +171,7 @@ func (f *KNNFilter) FilterSymmetric(m *mat.SymDense) {
 			}
 		}
 	default:
-		panic("Unsupported type for KNN filter")
+		return &ParamError{Field: "Typ", Value: f.Typ}
 	}
 
 	var (
@@ -175,11 +211,15 @@ func (f *KNNFilter) FilterSymmetric(m *mat.SymDense) {
 			rmMark(i, j)
 		}
 	}
+	return nil
 }
 
-func (f *KNNFilter) FilterDense(m *mat.Dense) {
+func (f *KNNFilter) FilterDense(m *mat.Dense) error {
 	var rmMark func(int, int)
 
+	if _, c := m.Dims(); f.K < 0 || f.K > c {
+		return &ParamError{Field: "K", Value: f.K}
+	}
 	switch f.Typ {
 	case AllKNN:
 		rmMark = func(i, j int) {
@@ -196,7 +236,7 @@ func (f *KNNFilter) FilterDense(m *mat.Dense) {
 			}
 		}
 	default:
-		panic("Unsupported type for KNN filter")
+		return &ParamError{Field: "Typ", Value: f.Typ}
 	}
 
 	var (
@@ -234,4 +274,5 @@ func (f *KNNFilter) FilterDense(m *mat.Dense) {
 			rmMark(i, j)
 		}
 	}
+	return nil
 }
@@ -6,6 +6,7 @@
 package matrix
 
 import (
+	"context"
 	"github.com/yinyajun/golearn/utils"
 	"gonum.org/v1/gonum/mat"
 	"math/rand"
@@ -31,9 +32,28 @@ func TestDistances_SysCartesian(t *testing.T) {
 	}
 	d.Filters = append(d.Filters, &KNNFilter{Typ: AnyKNN, K: 4})
 
-	res := d.SelfCartesian([]int{0, 1, 2, 3, 4, 5})
+	res, err := d.SelfCartesian([]int{0, 1, 2, 3, 4, 5})
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	showMatrix(res)
 	res2 := SubCartesian(res, []int{0, 1, 5}, []int{2, 4, 3})
 	showMatrix(res2)
 }
+
+func TestDistances_SelfCartesianContext(t *testing.T) {
+	d := &Distances{
+		Dist:        func(i, j int) float64 { return float64(i * j) },
+		NGoroutines: 4,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := d.SelfCartesianContext(ctx, utils.Range(0, 100, 1)); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	res, err := d.SelfCartesianContext(context.Background(), utils.Range(0, 10, 1))
+	if err != nil || res.At(3, 4) != 12 {
+		t.Errorf("unexpected result: %v, %v", res.At(3, 4), err)
+	}
+}
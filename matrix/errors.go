@@ -5,8 +5,12 @@
 
 package matrix
 
-import "fmt"
+import "github.com/yinyajun/golearn/utils"
 
-func ErrInvalidArgument(name string, data interface{}) string {
-	return fmt.Sprintf("Invalid argument: %s=%v", name, data)
-}
+var (
+	ErrInvalidArgument = utils.ErrInvalidArgument
+	ErrEmptyInput      = utils.ErrEmptyInput
+)
+
+// ParamError 参数不合法
+type ParamError = utils.ParamError
@@ -9,7 +9,7 @@ import (
 	"gonum.org/v1/gonum/mat"
 )
 
-func DenseMean(m *mat.Dense, axis int) *mat.VecDense {
+func DenseMean(m *mat.Dense, axis int) (*mat.VecDense, error) {
 	r, c := m.Dims()
 	var res *mat.VecDense
 	if axis == 0 {
@@ -18,7 +18,7 @@ func DenseMean(m *mat.Dense, axis int) *mat.VecDense {
 			res.AddVec(res, m.RowView(i))
 		}
 		res.ScaleVec(1/float64(r), res)
-		return res
+		return res, nil
 	}
 	if axis == 1 {
 		res = mat.NewVecDense(r, nil)
@@ -26,13 +26,16 @@ func DenseMean(m *mat.Dense, axis int) *mat.VecDense {
 			res.AddVec(res, m.ColView(i))
 		}
 		res.ScaleVec(1/float64(c), res)
-		return res
+		return res, nil
 	}
-	panic(ErrInvalidArgument("axis", axis))
+	return nil, &ParamError{Field: "axis", Value: axis}
 }
 
-func DenseAddVector(m *mat.Dense, vec mat.Vector, axis int) *mat.Dense {
+func DenseAddVector(m *mat.Dense, vec mat.Vector, axis int) (*mat.Dense, error) {
 	r, c := m.Dims()
+	if err := checkVector(r, c, vec, axis); err != nil {
+		return nil, err
+	}
 
 	if axis == 0 {
 		var row *mat.VecDense
@@ -40,22 +43,22 @@ func DenseAddVector(m *mat.Dense, vec mat.Vector, axis int) *mat.Dense {
 			row = m.RowView(i).(*mat.VecDense)
 			row.AddVec(row, vec)
 		}
-		return m
+		return m, nil
 	}
 
-	if axis == 1 {
-		var col *mat.VecDense
-		for i := 0; i < c; i++ {
-			col = m.ColView(i).(*mat.VecDense)
-			col.AddVec(col, vec)
-		}
-		return m
+	var col *mat.VecDense
+	for i := 0; i < c; i++ {
+		col = m.ColView(i).(*mat.VecDense)
+		col.AddVec(col, vec)
 	}
-	panic(ErrInvalidArgument("axis", axis))
+	return m, nil
 }
 
-func DenseSubVector(m *mat.Dense, vec mat.Vector, axis int) *mat.Dense {
+func DenseSubVector(m *mat.Dense, vec mat.Vector, axis int) (*mat.Dense, error) {
 	r, c := m.Dims()
+	if err := checkVector(r, c, vec, axis); err != nil {
+		return nil, err
+	}
 
 	if axis == 0 {
 		var row *mat.VecDense
@@ -63,21 +66,47 @@ func DenseSubVector(m *mat.Dense, vec mat.Vector, axis int) *mat.Dense {
 			row = m.RowView(i).(*mat.VecDense)
 			row.SubVec(row, vec)
 		}
-		return m
+		return m, nil
 	}
 
-	if axis == 1 {
-		var col *mat.VecDense
-		for i := 0; i < c; i++ {
-			col = m.ColView(i).(*mat.VecDense)
-			col.SubVec(col, vec)
-		}
-		return m
+	var col *mat.VecDense
+	for i := 0; i < c; i++ {
+		col = m.ColView(i).(*mat.VecDense)
+		col.SubVec(col, vec)
 	}
-	panic(ErrInvalidArgument("axis", axis))
+	return m, nil
 }
 
 func DenseSubScala(m *mat.Dense, num float64) *mat.Dense {
 	m.Apply(func(i, j int, v float64) float64 { return v + num }, m)
 	return m
 }
+
+// DenseNormalizeRows 将m的每一行原地缩放为单位L2范数（范数为0的行保持不变）
+func DenseNormalizeRows(m *mat.Dense) *mat.Dense {
+	r, _ := m.Dims()
+	for i := 0; i < r; i++ {
+		row := m.RowView(i).(*mat.VecDense)
+		if norm := mat.Norm(row, 2); norm > 0 {
+			row.ScaleVec(1/norm, row)
+		}
+	}
+	return m
+}
+
+// checkVector 检查axis以及vec的长度是否与r×c矩阵的行（axis=0）或列（axis=1）匹配
+func checkVector(r, c int, vec mat.Vector, axis int) error {
+	switch axis {
+	case 0:
+		if vec.Len() != c {
+			return &ParamError{Field: "vec.Len", Value: vec.Len()}
+		}
+	case 1:
+		if vec.Len() != r {
+			return &ParamError{Field: "vec.Len", Value: vec.Len()}
+		}
+	default:
+		return &ParamError{Field: "axis", Value: axis}
+	}
+	return nil
+}
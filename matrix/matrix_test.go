@@ -6,6 +6,7 @@
 package matrix
 
 import (
+	"errors"
 	"fmt"
 	"gonum.org/v1/gonum/mat"
 	"testing"
@@ -16,8 +17,8 @@ func TestDenseMean(t *testing.T) {
 	m.SetRow(0, []float64{1, 2, 3, 4})
 	m.SetRow(1, []float64{2, 2, 3, 4})
 	m.SetRow(2, []float64{3, 2, 3, 4})
-	a := DenseMean(m, 0)
-	b := DenseMean(m, 1)
+	a, _ := DenseMean(m, 0)
+	b, _ := DenseMean(m, 1)
 	fmt.Println(a)
 	fmt.Println(b)
 
@@ -27,3 +28,26 @@ func TestDenseMean(t *testing.T) {
 	}
 
 }
+
+func TestDenseMean_InvalidAxis(t *testing.T) {
+	m := mat.NewDense(2, 2, []float64{1, 2, 3, 4})
+	if _, err := DenseMean(m, 2); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument, got %v", err)
+	}
+	if _, err := DenseSubVector(m, mat.NewVecDense(3, nil), 0); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument, got %v", err)
+	}
+	f := &KNNFilter{Typ: "none", K: 1}
+	if err := f.FilterDense(m); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument, got %v", err)
+	}
+}
+
+func TestDenseNormalizeRows(t *testing.T) {
+	m := mat.NewDense(3, 2, []float64{3, 4, 0, 0, -1, 0})
+	DenseNormalizeRows(m)
+	want := mat.NewDense(3, 2, []float64{0.6, 0.8, 0, 0, -1, 0})
+	if !mat.EqualApprox(m, want, 1e-12) {
+		t.Errorf("expected %v, got %v", mat.Formatted(want), mat.Formatted(m))
+	}
+}
@@ -0,0 +1,282 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/18 15:26
+ */
+
+package matrix
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"github.com/yinyajun/golearn/utils"
+	"gonum.org/v1/gonum/mat"
+)
+
+var (
+	_ mat.Symmetric      = (*SymCSR)(nil)
+	_ mat.RowNonZeroDoer = (*SymCSR)(nil)
+)
+
+// SymCSR 以CSR格式存储的对称稀疏矩阵
+// 为了按行遍历，上下三角都会存储；每一行的列下标升序排列
+type SymCSR struct {
+	n       int
+	indptr  []int     // 第i行的非零元素为indices[indptr[i]:indptr[i+1]]
+	indices []int     // 列下标
+	data    []float64 // 元素值
+}
+
+// NewSymCSR 由三元组(rows[t], cols[t], vals[t])构造n阶对称稀疏矩阵
+// (i,j)与(j,i)只需给出其一；重复给出的元素会被累加
+func NewSymCSR(n int, rows, cols []int, vals []float64) *SymCSR {
+	if len(rows) != len(cols) || len(rows) != len(vals) {
+		panic(&ParamError{Field: "len(vals)", Value: len(vals)})
+	}
+	m := &SymCSR{n: n, indptr: make([]int, n+1)}
+
+	// 统计每行元素个数（非对角元素在两行中各存一份）
+	for t := range rows {
+		if rows[t] < 0 || rows[t] >= n || cols[t] < 0 || cols[t] >= n {
+			panic(mat.ErrIndexOutOfRange)
+		}
+		m.indptr[rows[t]+1]++
+		if rows[t] != cols[t] {
+			m.indptr[cols[t]+1]++
+		}
+	}
+	for i := 0; i < n; i++ {
+		m.indptr[i+1] += m.indptr[i]
+	}
+	var (
+		pos     = append([]int(nil), m.indptr[:n]...)
+		indices = make([]int, m.indptr[n])
+		data    = make([]float64, m.indptr[n])
+	)
+	put := func(i, j int, v float64) {
+		indices[pos[i]], data[pos[i]] = j, v
+		pos[i]++
+	}
+	for t := range rows {
+		put(rows[t], cols[t], vals[t])
+		if rows[t] != cols[t] {
+			put(cols[t], rows[t], vals[t])
+		}
+	}
+
+	// 每行按列下标排序并合并重复元素（原地压缩，写入位置不会超过读取位置）
+	m.indices = indices[:0]
+	m.data = data[:0]
+	for i := 0; i < n; i++ {
+		lo, hi := m.indptr[i], m.indptr[i+1]
+		sort.Sort(csrRow{indices[lo:hi], data[lo:hi]})
+		m.indptr[i] = len(m.indices)
+		for k := lo; k < hi; k++ {
+			if last := len(m.indices) - 1; last >= m.indptr[i] && m.indices[last] == indices[k] {
+				m.data[last] += data[k]
+				continue
+			}
+			m.indices = append(m.indices, indices[k])
+			m.data = append(m.data, data[k])
+		}
+	}
+	m.indptr[n] = len(m.indices)
+	return m
+}
+
+// SymCSRFrom 从对称矩阵中提取非零元素
+func SymCSRFrom(s mat.Symmetric) *SymCSR {
+	var (
+		n          = s.Symmetric()
+		rows, cols []int
+		data       []float64
+	)
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			if v := s.At(i, j); v != 0 {
+				rows, cols = append(rows, i), append(cols, j)
+				data = append(data, v)
+			}
+		}
+	}
+	return NewSymCSR(n, rows, cols, data)
+}
+
+func (m *SymCSR) Dims() (r, c int) { return m.n, m.n }
+
+func (m *SymCSR) At(i, j int) float64 {
+	if i < 0 || i >= m.n || j < 0 || j >= m.n {
+		panic(mat.ErrIndexOutOfRange)
+	}
+	row := m.indices[m.indptr[i]:m.indptr[i+1]]
+	k := sort.SearchInts(row, j)
+	if k < len(row) && row[k] == j {
+		return m.data[m.indptr[i]+k]
+	}
+	return 0
+}
+
+func (m *SymCSR) T() mat.Matrix { return m }
+
+func (m *SymCSR) Symmetric() int { return m.n }
+
+// NNZ 非零元素个数（非对角元素计两次）
+func (m *SymCSR) NNZ() int { return len(m.indices) }
+
+// DoRowNonZero 对第i行的每个非零元素调用fn（列下标升序）
+func (m *SymCSR) DoRowNonZero(i int, fn func(i, j int, v float64)) {
+	for k := m.indptr[i]; k < m.indptr[i+1]; k++ {
+		fn(i, m.indices[k], m.data[k])
+	}
+}
+
+// MulVecTo dst = m * x
+func (m *SymCSR) MulVecTo(dst *mat.VecDense, x mat.Vector) {
+	if x.Len() != m.n || dst.Len() != m.n {
+		panic(mat.ErrShape)
+	}
+	for i := 0; i < m.n; i++ {
+		var s float64
+		for k := m.indptr[i]; k < m.indptr[i+1]; k++ {
+			s += m.data[k] * x.AtVec(m.indices[k])
+		}
+		dst.SetVec(i, s)
+	}
+}
+
+// Sub 行列均为index的子矩阵，index[i]为子矩阵第i行在m中的行号
+func (m *SymCSR) Sub(index []int) *SymCSR {
+	var (
+		pos        = make(map[int]int, len(index))
+		rows, cols []int
+		vals       []float64
+	)
+	for i, x := range index {
+		pos[x] = i
+	}
+	for i, x := range index {
+		m.DoRowNonZero(x, func(_, y int, v float64) {
+			if j, ok := pos[y]; ok && j >= i {
+				rows, cols = append(rows, i), append(cols, j)
+				vals = append(vals, v)
+			}
+		})
+	}
+	return NewSymCSR(len(index), rows, cols, vals)
+}
+
+// ToSymDense 转为稠密矩阵
+func (m *SymCSR) ToSymDense() *mat.SymDense {
+	res := mat.NewSymDense(m.n, nil)
+	for i := 0; i < m.n; i++ {
+		m.DoRowNonZero(i, func(i, j int, v float64) {
+			if j >= i {
+				res.SetSym(i, j, v)
+			}
+		})
+	}
+	return res
+}
+
+// DoRowNonZero 对m第i行的每个非零元素调用fn，稀疏矩阵只遍历非零元素
+func DoRowNonZero(m mat.Matrix, i int, fn func(j int, v float64)) {
+	if s, ok := m.(mat.RowNonZeroDoer); ok {
+		s.DoRowNonZero(i, func(_, j int, v float64) { fn(j, v) })
+		return
+	}
+	_, c := m.Dims()
+	for j := 0; j < c; j++ {
+		if v := m.At(i, j); v != 0 {
+			fn(j, v)
+		}
+	}
+}
+
+// SparseSelfCartesian 直接生成kNN稀疏相似度矩阵，不构造n×n的稠密矩阵
+// 逐行计算Index中样本两两之间的d.Dist，每行只保留绝对值最大的K个（不含自身），
+// 再按Typ对称化：any为(i,j)或(j,i)之一是近邻即保留，all为互为近邻才保留。d.Filters被忽略
+func (f *KNNFilter) SparseSelfCartesian(d *Distances, Index []int) (*SymCSR, error) {
+	return f.SparseSelfCartesianContext(context.Background(), d, Index)
+}
+
+// SparseSelfCartesianContext 同SparseSelfCartesian，ctx取消时等待已启动的goroutine退出后返回ctx.Err()
+func (f *KNNFilter) SparseSelfCartesianContext(ctx context.Context, d *Distances, Index []int) (*SymCSR, error) {
+	n := len(Index)
+	if f.Typ != AnyKNN && f.Typ != AllKNN {
+		return nil, &ParamError{Field: "Typ", Value: f.Typ}
+	}
+	if f.K < 0 || f.K >= n {
+		return nil, &ParamError{Field: "K", Value: f.K}
+	}
+	if d.NGoroutines < 1 {
+		return nil, &ParamError{Field: "NGoroutines", Value: d.NGoroutines}
+	}
+
+	var (
+		neighbors = make([][]int, n)
+		values    = make([][]float64, n)
+	)
+	// 逐行求k近邻（每个goroutine计算一行）
+	err := utils.ParallelContext(ctx, n, d.NGoroutines, func(i int) {
+		var (
+			row  = make([]float64, n)
+			nums = make([]float64, n)
+		)
+		for j, y := range Index {
+			if j != i {
+				row[j] = d.Dist(Index[i], y)
+			}
+		}
+		for j := range row {
+			nums[j] = math.Abs(row[j])
+		}
+		nums[i] = -1 // 排除自身
+		neighbors[i] = append([]int(nil), utils.KBiggest(nums, f.K)...)
+		values[i] = make([]float64, len(neighbors[i]))
+		for t, j := range neighbors[i] {
+			values[i][t] = row[j]
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 对称化
+	var (
+		edges      = make(map[[2]int]float64)
+		count      = make(map[[2]int]int)
+		rows, cols []int
+		vals       []float64
+	)
+	for i := range neighbors {
+		for t, j := range neighbors[i] {
+			key := [2]int{i, j}
+			if j < i {
+				key = [2]int{j, i}
+			}
+			edges[key] = values[i][t]
+			count[key]++
+		}
+	}
+	for key, v := range edges {
+		if f.Typ == AllKNN && count[key] < 2 {
+			continue
+		}
+		rows, cols = append(rows, key[0]), append(cols, key[1])
+		vals = append(vals, v)
+	}
+	return NewSymCSR(n, rows, cols, vals), nil
+}
+
+type csrRow struct {
+	indices []int
+	data    []float64
+}
+
+func (r csrRow) Len() int           { return len(r.indices) }
+func (r csrRow) Less(i, j int) bool { return r.indices[i] < r.indices[j] }
+func (r csrRow) Swap(i, j int) {
+	r.indices[i], r.indices[j] = r.indices[j], r.indices[i]
+	r.data[i], r.data[j] = r.data[j], r.data[i]
+}
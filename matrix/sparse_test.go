@@ -0,0 +1,82 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/18 17:02
+ */
+
+package matrix
+
+import (
+	"testing"
+
+	"github.com/yinyajun/golearn/utils"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestNewSymCSR(t *testing.T) {
+	// (0,1)与(1,0)重复给出，应当累加
+	m := NewSymCSR(4, []int{0, 1, 2, 3, 3}, []int{1, 0, 2, 0, 1}, []float64{1, 2, 5, 3, 4})
+	expected := mat.NewSymDense(4, []float64{
+		0, 3, 0, 3,
+		3, 0, 0, 4,
+		0, 0, 5, 0,
+		3, 4, 0, 0,
+	})
+	if !mat.Equal(m, expected) {
+		t.Fatalf("unexpected matrix %v", mat.Formatted(m))
+	}
+	if m.NNZ() != 7 {
+		t.Errorf("expected 7 non-zeros, got %d", m.NNZ())
+	}
+	if !mat.Equal(SymCSRFrom(expected), expected) || !mat.Equal(m.ToSymDense(), expected) {
+		t.Errorf("conversion mismatch")
+	}
+
+	x := mat.NewVecDense(4, []float64{1, 2, 3, 4})
+	got, want := mat.NewVecDense(4, nil), mat.NewVecDense(4, nil)
+	m.MulVecTo(got, x)
+	want.MulVec(expected, x)
+	if !mat.EqualApprox(got, want, 1e-12) {
+		t.Errorf("expected %v, got %v", want.RawVector().Data, got.RawVector().Data)
+	}
+}
+
+func TestKNNFilter_SparseSelfCartesian(t *testing.T) {
+	// 一维点0,1,2,...,9，相似度为1/(1+距离^2)
+	d := &Distances{
+		Dist:        func(i, j int) float64 { return 1 / float64(1+(i-j)*(i-j)) },
+		NGoroutines: 4,
+	}
+	index := utils.Range(0, 10, 1)
+
+	union, err := (&KNNFilter{Typ: AnyKNN, K: 2}).SparseSelfCartesian(d, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			want := 0.0
+			switch {
+			case i-j == 1 || j-i == 1:
+				want = 0.5
+			case i+j == 2 && i != j, i+j == 16 && i != j: // 端点0和9的第2近邻
+				want = 0.2
+			}
+			if union.At(i, j) != want {
+				t.Fatalf("At(%d,%d): expected %v, got %v", i, j, want, union.At(i, j))
+			}
+		}
+	}
+
+	// 3近邻：0的近邻为1,2,3，1的近邻为0,2,3，而3的近邻不含0，all只保留互为近邻的边
+	mutual, err := (&KNNFilter{Typ: AllKNN, K: 3}).SparseSelfCartesian(d, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mutual.At(0, 1) == 0 || mutual.At(0, 3) != 0 || union.At(0, 3) != 0 {
+		t.Errorf("unexpected mutual kNN graph %v", mat.Formatted(mutual))
+	}
+
+	if _, err = (&KNNFilter{Typ: AnyKNN, K: 10}).SparseSelfCartesian(d, index); err == nil {
+		t.Errorf("expected error for K >= n")
+	}
+}
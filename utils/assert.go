@@ -6,32 +6,39 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
 )
 
+// Assert 条件不满足时panic；参数为单个error时直接panic该error，recover后可用errors.Is判断
 func Assert(condition bool, args ...interface{}) {
-	if !condition {
-		if msg := fmt.Sprint(args...); msg != "" {
-			fmt.Printf("Assert failed, %s\n", msg)
-		} else {
-			fmt.Println("Assert failed")
+	if condition {
+		return
+	}
+	if len(args) == 1 {
+		if err, ok := args[0].(error); ok {
+			panic(err)
 		}
 	}
+	if msg := fmt.Sprint(args...); msg != "" {
+		panic(fmt.Errorf("assert failed, %s", msg))
+	}
+	panic(errors.New("assert failed"))
 }
 
 func Assertf(condition bool, format string, args ...interface{}) {
-	if !condition {
-		if msg := fmt.Sprintf(format, args...); msg != "" {
-			fmt.Printf("Assert failed, %s\n", msg)
-		} else {
-			fmt.Println("Assert failed")
-		}
+	if condition {
+		return
 	}
+	if msg := fmt.Sprintf(format, args...); msg != "" {
+		panic(fmt.Errorf("assert failed, %s", msg))
+	}
+	panic(errors.New("assert failed"))
 }
 
 func AssertFunc(fn func() error) {
 	if err := fn(); err != nil {
-		fmt.Printf("AssertFunc failed: %v", err)
+		panic(fmt.Errorf("AssertFunc failed: %w", err))
 	}
 }
 
@@ -41,3 +48,14 @@ func If(condition bool, trueVal, falseVal interface{}) interface{} {
 	}
 	return falseVal
 }
+
+// BoolsToInts 将[]bool转为[]int（true为1，false为0），例如二分的结果转为类标签
+func BoolsToInts(a []bool) []int {
+	res := make([]int, len(a))
+	for i, b := range a {
+		if b {
+			res[i] = 1
+		}
+	}
+	return res
+}
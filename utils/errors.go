@@ -5,8 +5,24 @@
 
 package utils
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
-func ErrInvalidArgument(name string, data interface{}) string {
-	return fmt.Sprintf("Invalid argument: %s=%v", name, data)
+var (
+	ErrInvalidArgument = errors.New("invalid argument")
+	ErrEmptyInput      = errors.New("empty input")
+)
+
+// ParamError 参数不合法，可以通过errors.Is(err, ErrInvalidArgument)或errors.As判断
+type ParamError struct {
+	Field string      // 参数名
+	Value interface{} // 参数值
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("%s: %s=%v", ErrInvalidArgument, e.Field, e.Value)
 }
+
+func (e *ParamError) Unwrap() error { return ErrInvalidArgument }
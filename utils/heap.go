@@ -90,3 +90,95 @@ func sink(index []int, lo, hi int, less func(i, j int) bool) {
 		root = m
 	}
 }
+
+// IndexMaxHeap 索引大顶堆，元素为[0,n)中的下标，可以修改或删除任意下标的key
+// 用于需要频繁更新优先级的场景（例如FM算法中的增益）
+type IndexMaxHeap struct {
+	heap []int     // 堆中的下标
+	pos  []int     // pos[i]为下标i在heap中的位置，不在堆中时为-1
+	keys []float64 // keys[i]为下标i的key
+}
+
+func NewIndexMaxHeap(n int) *IndexMaxHeap {
+	h := &IndexMaxHeap{pos: make([]int, n), keys: make([]float64, n)}
+	for i := range h.pos {
+		h.pos[i] = -1
+	}
+	return h
+}
+
+func (h *IndexMaxHeap) Len() int { return len(h.heap) }
+
+func (h *IndexMaxHeap) Contains(i int) bool { return h.pos[i] >= 0 }
+
+func (h *IndexMaxHeap) Key(i int) float64 { return h.keys[i] }
+
+// Push 插入下标i，i已在堆中时等价于Update
+func (h *IndexMaxHeap) Push(i int, key float64) {
+	if h.Contains(i) {
+		h.Update(i, key)
+		return
+	}
+	h.keys[i] = key
+	h.pos[i] = len(h.heap)
+	h.heap = append(h.heap, i)
+	h.swim(h.pos[i])
+}
+
+// Update 修改下标i的key
+func (h *IndexMaxHeap) Update(i int, key float64) {
+	Assert(h.Contains(i), "index not in heap")
+	old := h.keys[i]
+	h.keys[i] = key
+	if key > old {
+		h.swim(h.pos[i])
+	} else {
+		h.down(h.pos[i])
+	}
+}
+
+// Max 返回key最大的下标（不删除）
+func (h *IndexMaxHeap) Max() (int, float64) {
+	Assert(h.Len() > 0, "heap is empty")
+	return h.heap[0], h.keys[h.heap[0]]
+}
+
+// Remove 删除下标i
+func (h *IndexMaxHeap) Remove(i int) {
+	Assert(h.Contains(i), "index not in heap")
+	p, last := h.pos[i], len(h.heap)-1
+	h.exchange(p, last)
+	h.heap = h.heap[:last]
+	h.pos[i] = -1
+	if p < last {
+		h.swim(p)
+		h.down(p)
+	}
+}
+
+func (h *IndexMaxHeap) exchange(a, b int) {
+	h.heap[a], h.heap[b] = h.heap[b], h.heap[a]
+	h.pos[h.heap[a]], h.pos[h.heap[b]] = a, b
+}
+
+func (h *IndexMaxHeap) swim(p int) {
+	for p > 0 && h.keys[h.heap[(p-1)/2]] < h.keys[h.heap[p]] {
+		h.exchange(p, (p-1)/2)
+		p = (p - 1) / 2
+	}
+}
+
+func (h *IndexMaxHeap) down(p int) {
+	n := len(h.heap)
+	for 2*p+1 < n {
+		m := 2*p + 1
+		if m+1 < n && h.keys[h.heap[m]] < h.keys[h.heap[m+1]] {
+			m++
+		}
+		if h.keys[h.heap[p]] >= h.keys[h.heap[m]] {
+			break
+		}
+		h.exchange(p, m)
+		p = m
+	}
+}
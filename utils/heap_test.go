@@ -49,3 +49,34 @@ func TestKSmallest(t *testing.T) {
 	floats.Argsort(nums, idx)
 	fmt.Println(idx[:k])
 }
+
+func TestIndexMaxHeap(t *testing.T) {
+	var (
+		r    = rand.New(rand.NewSource(1))
+		n    = 50
+		h    = NewIndexMaxHeap(n)
+		keys = make([]float64, n)
+	)
+	for i := 0; i < n; i++ {
+		keys[i] = r.Float64()
+		h.Push(i, keys[i])
+	}
+	for i := 0; i < n; i += 3 {
+		keys[i] = r.Float64()*2 - 1
+		h.Update(i, keys[i])
+	}
+	for i := 1; i < n; i += 7 {
+		h.Remove(i)
+		keys[i] = -1e9
+	}
+
+	last := 1e9
+	for h.Len() > 0 {
+		i, key := h.Max()
+		if key != keys[i] || key > last {
+			t.Fatalf("unexpected max %d: %v (last %v)", i, key, last)
+		}
+		last = key
+		h.Remove(i)
+	}
+}
@@ -0,0 +1,34 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/26 14:20
+ */
+
+package utils
+
+import "context"
+
+// ParallelContext 以nGoroutines的并发度对[0,n)中的每个i执行fn（每个i一个goroutine）
+// ctx取消后不再启动新的goroutine，等待已启动的goroutine完成后返回ctx.Err()
+func ParallelContext(ctx context.Context, n, nGoroutines int, fn func(i int)) error {
+	limit := make(chan int, nGoroutines)
+	for i := 0; i < n; i++ {
+		if ctx.Err() != nil {
+			break
+		}
+		limit <- 1
+		go func(i int) {
+			fn(i)
+			<-limit
+		}(i)
+	}
+	for i := 0; i < nGoroutines; i++ { // 确保最后一批goroutine完成job
+		limit <- 1
+	}
+	close(limit)
+	return ctx.Err()
+}
+
+// Parallel 同ParallelContext，不可取消
+func Parallel(n, nGoroutines int, fn func(i int)) {
+	_ = ParallelContext(context.Background(), n, nGoroutines, fn)
+}
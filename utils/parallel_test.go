@@ -0,0 +1,33 @@
+/*
+* @Author: Yajun
+* @Date:   2021/12/26 14:35
+ */
+
+package utils
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParallelContext(t *testing.T) {
+	res := make([]int, 100)
+	if err := ParallelContext(context.Background(), len(res), 4, func(i int) { res[i] = i * i }); err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range res {
+		if v != i*i {
+			t.Fatalf("job %d is not done", i)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := false
+	if err := ParallelContext(ctx, 10, 2, func(i int) { called = true }); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if called {
+		t.Errorf("no job should start after cancel")
+	}
+}
@@ -8,6 +8,7 @@ package utils
 import (
 	"math"
 	"math/rand"
+	"time"
 )
 
 func Range(start, end, stride int) []int {
@@ -20,16 +21,31 @@ func Range(start, end, stride int) []int {
 	return ans
 }
 
+// NewRand 由src构造随机数生成器（src为nil时以当前时间为种子），不会触碰全局的随机数状态
+func NewRand(src rand.Source) *rand.Rand {
+	if src == nil {
+		src = rand.NewSource(time.Now().UnixNano())
+	}
+	return rand.New(src)
+}
+
 type Sampler struct {
 	cardinality int
 	dist        []float64
 	sum         float64
+	rand        *rand.Rand
 }
 
 func NewSampler(c int) *Sampler {
+	return NewRandSampler(c, NewRand(nil))
+}
+
+// NewRandSampler 使用指定的随机数生成器采样，相同状态的r得到相同的采样序列
+func NewRandSampler(c int, r *rand.Rand) *Sampler {
 	return &Sampler{
 		cardinality: c,
 		dist:        make([]float64, c),
+		rand:        r,
 	}
 }
 
@@ -54,7 +70,7 @@ func (p *Sampler) Sample() int {
 		k    int
 	)
 	k = 0
-	t = rand.Float64() * p.sum
+	t = p.rand.Float64() * p.sum
 	for k = range p.dist {
 		s += p.dist[k]
 		if t <= s {